@@ -1,25 +1,37 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/syberalexis/linky-exporter/pkg/api"
+	"github.com/syberalexis/linky-exporter/pkg/config"
 	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/influx"
+	"github.com/syberalexis/linky-exporter/pkg/mqtt"
+	"github.com/syberalexis/linky-exporter/pkg/notify"
 	"github.com/syberalexis/linky-exporter/pkg/prom"
+	"github.com/syberalexis/linky-exporter/pkg/remote"
 )
 
 var (
 	// Default variables
-	version          = "dev"
-	defaultPort      = 9901
-	defaultAddress   = "0.0.0.0"
-	defaultBaudRate  = 1200
-	defaultFrameSize = 7
-	defaultParity    = "ParityNone"
-	defaultStopBits  = "Stop1"
+	version         = "dev"
+	defaultInterval = 2 * time.Second
 
 	// Flags
+	configFile string
 	debug      bool
 	address    string
 	port       int
@@ -31,105 +43,316 @@ var (
 	size       int
 	parity     string
 	stopBits   string
+	webhook    string
+	readyAge   time.Duration
+	mqttBroker string
+	influxURL  string
+	remoteURL  string
+
+	// Web flags
+	listenAddresses []string
+	systemdSocket   bool
+	webConfigFile   string
+
+	// Record flags
+	output      string
+	indexOutput string
+	duration    time.Duration
+
+	// Simulate flags
+	contract        string
+	profile         string
+	simulatorOutput string
+	interval        time.Duration
+)
+
+// Exit codes
+const (
+	exitError  = 1 // Runtime failure
+	exitUsage  = 2 // Invalid command line
+	exitConfig = 3 // Invalid configuration
 )
 
+// Error carrying the process exit code
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// Attach an exit code to an error
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitCodeError{code: code, err: err}
+}
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:     "linky-exporter",
 		Version: version,
 		Short:   "Prometheus exporter for Linky smart meters",
-		Run: func(cmd *cobra.Command, args []string) {
-			run()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd)
 		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
 
 	// Define flags
+	rootCmd.PersistentFlags().StringVarP(
+		&configFile,
+		"config",
+		"c",
+		"",
+		"YAML configuration file, flags override its values")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug mode")
 	rootCmd.PersistentFlags().StringVarP(
 		&address,
 		"address",
 		"a",
-		defaultAddress,
+		config.DefaultAddress,
 		"Listen address")
-	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", defaultPort, "Listen port")
+	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", config.DefaultPort, "Listen port")
 	rootCmd.PersistentFlags().BoolVar(&auto, "auto", false, "Automatique mode")
 	rootCmd.PersistentFlags().BoolVar(&historical, "historical", false, "Historical mode")
 	rootCmd.PersistentFlags().BoolVar(&standard, "standard", false, "Standard mode")
-	rootCmd.PersistentFlags().StringVarP(&device, "device", "d", "", "Device to read")
-	err := rootCmd.MarkPersistentFlagRequired("device")
-	if err != nil {
-		slog.Error("Error during flag parsing", "error", err)
-		os.Exit(1)
-	}
-	rootCmd.PersistentFlags().IntVarP(&baudRate, "baud", "b", defaultBaudRate, "Baud rate")
-	rootCmd.PersistentFlags().IntVar(&size, "size", defaultFrameSize, "Serial frame size")
+	rootCmd.PersistentFlags().StringVarP(&device, "device", "d", "", "Device to read (serial device path, tcp://host:port or file:///path/capture.tic)")
+	rootCmd.PersistentFlags().IntVarP(&baudRate, "baud", "b", 0, "Baud rate, mode default if not set")
+	rootCmd.PersistentFlags().IntVar(&size, "size", 0, "Serial frame size, mode default if not set")
 	rootCmd.PersistentFlags().StringVar(
 		&parity,
 		"parity",
-		defaultParity,
-		"Serial parity (ParityNone, N, ParityOdd, O, ParityEven, E, ParityMark, M, ParitySpace, S)")
+		"",
+		"Serial parity (ParityNone, N, ParityOdd, O, ParityEven, E, ParityMark, M, ParitySpace, S), mode default if not set")
 	rootCmd.PersistentFlags().StringVar(
 		&stopBits,
 		"stopbits",
-		defaultStopBits,
-		"Serial stopbits (Stop1, 1, Stop1Half, 15, Stop2, 2)")
+		"",
+		"Serial stopbits (Stop1, 1, Stop1Half, 15, Stop2, 2), mode default if not set")
+	rootCmd.Flags().StringSliceVar(
+		&listenAddresses,
+		"web.listen-address",
+		nil,
+		"Addresses to listen on, repeatable, --address and --port if not set")
+	rootCmd.Flags().BoolVar(
+		&systemdSocket,
+		"web.systemd-socket",
+		false,
+		"Use systemd socket activation listeners instead of port listeners (Linux only)")
+	rootCmd.Flags().StringVar(
+		&webConfigFile,
+		"web.config.file",
+		"",
+		"Path to a web configuration file enabling TLS or authentication, see exporter-toolkit")
+	rootCmd.Flags().DurationVar(
+		&readyAge,
+		"ready-max-age",
+		core.FrameTimeout,
+		"Maximum age of the last frame of each meter for /readyz to succeed")
+	rootCmd.Flags().StringVar(
+		&mqttBroker,
+		"mqtt.broker",
+		"",
+		"MQTT broker URL like tcp://localhost:1883 receiving frames with Home Assistant discovery")
+	rootCmd.Flags().StringVar(
+		&influxURL,
+		"influxdb.url",
+		"",
+		"InfluxDB v2 URL like http://localhost:8086 receiving every frame, bucket and token from the configuration file")
+	rootCmd.Flags().StringVar(
+		&remoteURL,
+		"remote-write.url",
+		"",
+		"Prometheus remote write URL receiving the metrics, for meters which can't be scraped")
+	rootCmd.Flags().StringVar(
+		&webhook,
+		"message-webhook",
+		"",
+		"URL receiving a JSON POST when a meter message (MSG1, MSG2) changes")
+
+	recordCmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record raw TIC data to a capture file, replayable with --device file://",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return record(cmd)
+		},
+	}
+	recordCmd.Flags().StringVarP(&output, "out", "o", "", "Capture file to write")
+	err := recordCmd.MarkFlagRequired("out")
+	if err != nil {
+		slog.Error("Error during flag parsing", "error", err)
+		os.Exit(1)
+	}
+	recordCmd.Flags().DurationVar(&duration, "duration", 0, "Recording duration, until interrupted if 0")
+	recordCmd.Flags().StringVar(
+		&indexOutput,
+		"index",
+		"",
+		"Optional index file receiving the offset and timestamp of each frame")
+	rootCmd.AddCommand(recordCmd)
+
+	simulateCmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate a meter writing TIC frames to a pseudo terminal or TCP clients",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return simulate()
+		},
+	}
+	simulateCmd.Flags().StringVar(&contract, "contract", core.ContractBase, "Simulated contract (BASE, HCHP, EJP, BBR)")
+	simulateCmd.Flags().StringVar(
+		&profile,
+		"profile",
+		"",
+		"Daily load profile in VA like \"00:00=300,07:00=1500,19:00=3000\", a household curve if empty")
+	simulateCmd.Flags().StringVar(&simulatorOutput, "output", "pty", "Where to write frames (pty or tcp://host:port)")
+	simulateCmd.Flags().DurationVar(&interval, "interval", defaultInterval, "Interval between frames")
+	rootCmd.AddCommand(simulateCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		slog.Error("Error executing command", "error", err)
-		os.Exit(1)
+		code := exitError
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		}
+		os.Exit(code)
 	}
 }
 
 // Main run function
-func run() {
-	if debug {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-		slog.Info("Debug mode enabled !")
+func run(cmd *cobra.Command) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
 	}
+	enableDebug(cfg.Debug)
+
+	// Run until interrupted, then stop serving before closing the devices
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Read TIC frames of each meter in background, a failing meter doesn't stop the others
+	meters := make(map[string]*core.LinkyConnector)
+	for i := range cfg.Devices {
+		name := cfg.Devices[i].MeterName()
+		connector, err := newConnector(&cfg.Devices[i])
+		if err != nil {
+			return err
+		}
+		connector.Start()
+		defer connector.Stop()
+		meters[name] = connector
+
+		// Notify meter message changes
+		notifier := notify.MessageNotifier{Meter: name, Webhook: cfg.Messages.Webhook}
+		go notifier.Run(ctx, connector)
+	}
+
+	// Serve health checks and JSON API next to metrics
+	http.Handle("/healthz", &api.HealthHandler{})
+	http.Handle("/readyz", &api.ReadinessHandler{Meters: meters, MaxAge: cfg.ReadyMaxAge})
+	if cfg.API.Enabled {
+		http.Handle("/api/schedule/tomorrow", &api.ScheduleHandler{Meters: meters})
+		http.Handle("/api/v1/frame", &api.FrameHandler{Meters: meters})
+		http.Handle("/api/v1/raw", &api.RawHandler{Meters: meters})
+		http.Handle("/api/v1/stream", &api.StreamHandler{Meters: meters, Done: ctx.Done()})
+	}
+
+	// Push frames to the outputs in background
+	var outputs sync.WaitGroup
+	if cfg.MQTT.Broker != "" {
+		publisher := mqtt.LinkyPublisher{
+			Broker:   cfg.MQTT.Broker,
+			ClientId: cfg.MQTT.ClientId,
+			Username: cfg.MQTT.Username,
+			Password: cfg.MQTT.Password,
+			Topics:   mqtt.Topics{Prefix: cfg.MQTT.TopicPrefix, DiscoveryPrefix: cfg.MQTT.DiscoveryPrefix},
+		}
+		outputs.Go(func() { publisher.Run(ctx, meters) })
+	}
+	if cfg.InfluxDB.URL != "" {
+		writer := influx.LinkyWriter{
+			URL:           cfg.InfluxDB.URL,
+			Org:           cfg.InfluxDB.Org,
+			Bucket:        cfg.InfluxDB.Bucket,
+			Token:         cfg.InfluxDB.Token,
+			BatchSize:     cfg.InfluxDB.BatchSize,
+			FlushInterval: cfg.InfluxDB.FlushInterval,
+			SpoolDir:      cfg.InfluxDB.SpoolDir,
+			SpoolMaxSize:  cfg.InfluxDB.SpoolMaxSize,
+		}
+		outputs.Go(func() { writer.Run(ctx, meters) })
+	}
+	if cfg.RemoteWrite.URL != "" {
+		remoteWriter := remote.LinkyRemoteWriter{
+			URL:             cfg.RemoteWrite.URL,
+			Username:        cfg.RemoteWrite.Username,
+			Password:        cfg.RemoteWrite.Password,
+			BearerToken:     cfg.RemoteWrite.BearerToken,
+			Interval:        cfg.RemoteWrite.Interval,
+			WALDir:          cfg.RemoteWrite.WALDir,
+			WALMaxSize:      cfg.RemoteWrite.WALMaxSize,
+			DisabledMetrics: cfg.Metrics.DisabledMetrics(),
+		}
+		outputs.Go(func() {
+			err := remoteWriter.Run(ctx, meters)
+			if err != nil {
+				slog.Error("Unable to run remote write", "error", err)
+			}
+		})
+	}
+
+	// Run exporter
+	exporter := prom.LinkyExporter{
+		Address:         cfg.Address,
+		Port:            cfg.Port,
+		ListenAddresses: cfg.Web.ListenAddresses,
+		SystemdSocket:   cfg.Web.SystemdSocket,
+		WebConfigFile:   cfg.Web.ConfigFile,
+		DisabledMetrics: cfg.Metrics.DisabledMetrics(),
+	}
+	err = exporter.Run(ctx, meters)
+
+	// Let outputs flush and announce they are going offline
+	cancel()
+	outputs.Wait()
 
-	// Checks before running
-	_, err := os.Stat(device)
 	if err != nil {
-		slog.Error("Device not found", "error", err)
-	}
-
-	// Parse parameters
-	connector := core.LinkyConnector{Device: device}
-	detect := auto
-	if !detect {
-		if standard {
-			connector.Mode = core.Standard
-			connector.BaudRate = core.Standard.BaudRate
-			connector.FrameSize = core.Standard.FrameSize
-			connector.Parity = core.Standard.Parity
-			connector.StopBits = core.Standard.StopBits
-		} else if historical {
-			connector.Mode = core.Historical
-			connector.BaudRate = core.Historical.BaudRate
-			connector.FrameSize = core.Historical.FrameSize
-			connector.Parity = core.Historical.Parity
-			connector.StopBits = core.Historical.StopBits
-		} else {
-			detect = true
-		}
-		if baudRate != 0 {
-			connector.BaudRate = baudRate
-		}
-		if size != 0 {
-			connector.FrameSize = size
-		}
-		if parity != "" {
-			slog.Debug("Parsing parity", "parity", parity)
-			connector.Parity = core.ParseParity(parity)
-		}
-		if stopBits != "" {
-			slog.Debug("Parsing stop bits configuration", "stopBits", stopBits)
-			connector.StopBits = core.ParseStopBits(stopBits)
-		}
-	}
-
-	// Auto detection mode
-	if detect {
+		return fmt.Errorf("error while serving metrics: %w", err)
+	}
+	slog.Info("Exporter stopped")
+	return nil
+}
+
+// Record run function
+func record(cmd *cobra.Command) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	enableDebug(cfg.Debug)
+	if len(cfg.Devices) > 1 {
+		return withExitCode(exitConfig, fmt.Errorf("only one device can be recorded at a time, %d configured", len(cfg.Devices)))
+	}
+	connector, err := newConnector(&cfg.Devices[0])
+	if err != nil {
+		return err
+	}
+
+	// Recording needs the serial configuration before starting
+	if connector.AutoDetect {
 		err := connector.Detect()
 		slog.Debug("Connector configuration",
 			"device", connector.Device,
@@ -139,11 +362,209 @@ func run() {
 			"parity", connector.Parity,
 			"stopbits", connector.StopBits)
 		if err != nil {
-			slog.Error("Error during auto detection", "error", err)
+			return withExitCode(exitError, fmt.Errorf("unable to detect TIC mode: %w", err))
 		}
 	}
 
-	// Run exporter
-	exporter := prom.LinkyExporter{Address: address, Port: port}
-	exporter.Run(&connector)
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("unable to create capture file: %w", err)
+	}
+	defer closeFile(file)
+
+	var index io.Writer
+	if indexOutput != "" {
+		indexFile, err := os.Create(indexOutput)
+		if err != nil {
+			return fmt.Errorf("unable to create index file: %w", err)
+		}
+		defer closeFile(indexFile)
+		index = indexFile
+	}
+
+	// Record until duration elapsed or interrupted
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	written, err := connector.Record(ctx, file, index)
+	slog.Info("Recording ended", "file", output, "bytes", written)
+	if err != nil {
+		return fmt.Errorf("error while recording: %w", err)
+	}
+	return nil
+}
+
+// Simulate run function
+func simulate() error {
+	enableDebug(debug)
+
+	mode := core.Historical
+	if standard {
+		mode = core.Standard
+	}
+	loadProfile := core.DefaultLoadProfile
+	if profile != "" {
+		var err error
+		loadProfile, err = core.ParseLoadProfile(profile)
+		if err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("invalid load profile: %w", err))
+		}
+	}
+	simulator, err := core.NewLinkySimulator(mode, contract, loadProfile)
+	if err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("invalid simulator configuration: %w", err))
+	}
+
+	var writer io.WriteCloser
+	if simulatorOutput == "pty" {
+		ptyOutput, err := core.OpenPtyOutput()
+		if err != nil {
+			return fmt.Errorf("unable to open pseudo terminal: %w", err)
+		}
+		slog.Info("Simulating meter on pseudo terminal", "device", ptyOutput.Device())
+		writer = ptyOutput
+	} else if address, found := strings.CutPrefix(simulatorOutput, "tcp://"); found {
+		tcpOutput, err := core.ListenTCPOutput(address)
+		if err != nil {
+			return fmt.Errorf("unable to listen: %w", err)
+		}
+		slog.Info("Simulating meter on TCP", "device", "tcp://"+tcpOutput.Address())
+		writer = tcpOutput
+	} else {
+		return withExitCode(exitUsage, fmt.Errorf("invalid simulator output %q, must be pty or tcp://host:port", simulatorOutput))
+	}
+	defer func() { _ = writer.Close() }()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	err = simulator.Run(ctx, writer, interval)
+	if err != nil {
+		return fmt.Errorf("error while simulating: %w", err)
+	}
+	return nil
+}
+
+// Close a written file and log failures
+func closeFile(file *os.File) {
+	err := file.Close()
+	if err != nil {
+		slog.Error("Failed to close file", "file", file.Name(), "error", err)
+	}
+}
+
+// Enable debug logs if requested
+func enableDebug(enabled bool) {
+	if enabled {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Info("Debug mode enabled !")
+	}
+}
+
+// Load configuration file if any, override it with the flags set and validate it
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	cfg := config.Default()
+	if configFile != "" {
+		var err error
+		cfg, err = config.Load(configFile)
+		if err != nil {
+			return cfg, withExitCode(exitConfig, fmt.Errorf("unable to load configuration: %w", err))
+		}
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("debug") {
+		cfg.Debug = debug
+	}
+	if flags.Changed("address") {
+		cfg.Address = address
+	}
+	if flags.Changed("port") {
+		cfg.Port = port
+	}
+	if flags.Changed("web.listen-address") {
+		cfg.Web.ListenAddresses = listenAddresses
+	}
+	if flags.Changed("web.systemd-socket") {
+		cfg.Web.SystemdSocket = systemdSocket
+	}
+	if flags.Changed("web.config.file") {
+		cfg.Web.ConfigFile = webConfigFile
+	}
+	if flags.Changed("ready-max-age") {
+		cfg.ReadyMaxAge = readyAge
+	}
+	if flags.Changed("remote-write.url") {
+		cfg.RemoteWrite.URL = remoteURL
+	}
+	if flags.Changed("influxdb.url") {
+		cfg.InfluxDB.URL = influxURL
+	}
+	if flags.Changed("mqtt.broker") {
+		cfg.MQTT.Broker = mqttBroker
+	}
+	if flags.Changed("message-webhook") {
+		cfg.Messages.Webhook = webhook
+	}
+
+	// Device flags apply to the first device
+	deviceFlags := []string{"device", "auto", "historical", "standard", "baud", "size", "parity", "stopbits"}
+	for _, name := range deviceFlags {
+		if flags.Changed(name) && len(cfg.Devices) == 0 {
+			cfg.Devices = append(cfg.Devices, config.DeviceConfig{})
+		}
+	}
+	if len(cfg.Devices) > 0 {
+		deviceConfig := &cfg.Devices[0]
+		if flags.Changed("device") {
+			deviceConfig.Device = device
+		}
+		if flags.Changed("auto") && auto {
+			deviceConfig.Mode = config.ModeAuto
+		} else if flags.Changed("standard") && standard {
+			deviceConfig.Mode = config.ModeStandard
+		} else if flags.Changed("historical") && historical {
+			deviceConfig.Mode = config.ModeHistorical
+		}
+		if flags.Changed("baud") {
+			deviceConfig.BaudRate = baudRate
+		}
+		if flags.Changed("size") {
+			deviceConfig.FrameSize = size
+		}
+		if flags.Changed("parity") {
+			deviceConfig.Parity = parity
+		}
+		if flags.Changed("stopbits") {
+			deviceConfig.StopBits = stopBits
+		}
+	}
+
+	err := cfg.Validate()
+	if err != nil {
+		return cfg, withExitCode(exitConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return cfg, nil
+}
+
+// Build connector from device configuration, detecting TIC mode in background if needed
+func newConnector(deviceConfig *config.DeviceConfig) (*core.LinkyConnector, error) {
+	// Checks before running
+	if core.IsLocalDevice(deviceConfig.Device) {
+		_, err := os.Stat(deviceConfig.Device)
+		if err != nil {
+			slog.Error("Device not found", "error", err)
+		}
+	}
+
+	connector, detect, err := deviceConfig.Connector()
+	if err != nil {
+		return nil, withExitCode(exitConfig, err)
+	}
+	connector.AutoDetect = detect
+
+	return connector, nil
 }
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Units of the standard mode values, by field name
+var standardUnits = map[string]string{
+	"East": "Wh", "Eait": "Wh",
+	"Easf01": "Wh", "Easf02": "Wh", "Easf03": "Wh", "Easf04": "Wh", "Easf05": "Wh",
+	"Easf06": "Wh", "Easf07": "Wh", "Easf08": "Wh", "Easf09": "Wh", "Easf10": "Wh",
+	"Easd01": "Wh", "Easd02": "Wh", "Easd03": "Wh", "Easd04": "Wh",
+	"Erq1": "VArh", "Erq2": "VArh", "Erq3": "VArh", "Erq4": "VArh",
+	"Irms1": "A", "Irms2": "A", "Irms3": "A",
+	"Urms1": "V", "Urms2": "V", "Urms3": "V",
+	"Umoy1": "V", "Umoy2": "V", "Umoy3": "V",
+	"Pref": "kVA", "Pcoup": "kVA",
+	"Sinsts": "VA", "Sinsts1": "VA", "Sinsts2": "VA", "Sinsts3": "VA", "Sinsti": "VA",
+	"Smaxsn": "VA", "Smaxsn1": "VA", "Smaxsn2": "VA", "Smaxsn3": "VA",
+	"Smaxsnly": "VA", "Smaxsn1ly": "VA", "Smaxsn2ly": "VA", "Smaxsn3ly": "VA",
+	"Smaxin": "VA", "Smaxinly": "VA",
+	"Ccasn": "W", "Ccasnly": "W", "Ccain": "W", "Ccainly": "W",
+}
+
+// Units of the historical mode values, by field name
+var historicalUnits = map[string]string{
+	"Isousc": "A",
+	"Base":   "Wh", "Hchc": "Wh", "Hchp": "Wh", "Ejphn": "Wh", "Ejphpn": "Wh",
+	"Bbrhcjb": "Wh", "Bbrhpjb": "Wh", "Bbrhcjw": "Wh", "Bbrhpjw": "Wh", "Bbrhcjr": "Wh", "Bbrhpjr": "Wh",
+	"Pejp":  "min",
+	"Iinst": "A", "Iinst1": "A", "Iinst2": "A", "Iinst3": "A", "Adps": "A",
+	"Imax": "A", "Imax1": "A", "Imax2": "A", "Imax3": "A",
+	"Pmax": "W",
+	"Papp": "VA",
+}
+
+// Fields decoded from a label, when not the field named after the label and its horodate
+var labelFields = map[string][]string{
+	"stge": {
+		"DryContactStatus", "CutOffDeviceStatus", "LinkyTerminalShieldStatus", "SurgeStatus",
+		"ReferencePowerExceededStatus", "ConsumptionStatus", "EnergyDirectionStatus", "ContractTypePriceStatus",
+		"ContractTypePriceDistributorStatus", "ClockStatus", "TicStatus", "EuridisLinkStatus", "CPLStatus",
+		"CPLSyncStatus", "TempoContractColorStatus", "TempoContractNextDayColorStatus", "MovingPeakNoticeStatus",
+		"MovingPeakStatus",
+	},
+	"relais":   {"Relai1", "Relai2", "Relai3", "Relai4", "Relai5", "Relai6", "Relai7", "Relai8"},
+	"ejphpm":   {"Ejphpn"},
+	"motdetat": {"Motdetat", "StateWord"},
+	"ppot":     {"Ppot", "PhasePotential1", "PhasePotential2", "PhasePotential3"},
+}
+
+// Suffixes of the labels of the previous period or the next day, replaced in the field names
+var labelSuffixes = strings.NewReplacer("-1", "ly", "+1", "nd")
+
+// Frame is the latest decoded frame of a meter, values of groups not sent by the meter are omitted
+type Frame struct {
+	Meter      string                `json:"meter"`
+	Mode       string                `json:"mode"`
+	ReceivedAt time.Time             `json:"received_at"`
+	Values     map[string]FrameValue `json:"values"` // By snake case field name, like sinsts or easf01
+}
+
+// FrameValue is a decoded value with its unit
+type FrameValue struct {
+	Value any    `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// RawFrame is the latest frame of a meter as received, groups with an invalid checksum are omitted
+type RawFrame struct {
+	Meter      string     `json:"meter"`
+	ReceivedAt time.Time  `json:"received_at"`
+	Groups     []RawGroup `json:"groups"`
+}
+
+// RawGroup is an information group of a frame
+type RawGroup struct {
+	Label    string `json:"label"`
+	Horodate string `json:"horodate,omitempty"`
+	Value    string `json:"value"`
+	Checksum string `json:"checksum"`
+}
+
+// FrameHandler serves the decoded values of the latest frame
+type FrameHandler struct {
+	Meters Meters
+}
+
+func (handler *FrameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, connector, ok := handler.Meters.selectConnector(w, r)
+	if !ok {
+		return
+	}
+	frame := connector.LastFrame()
+	if frame == nil {
+		writeError(w, http.StatusServiceUnavailable, "no frame received yet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewFrame(name, frame))
+}
+
+// RawHandler serves the groups of the latest frame
+type RawHandler struct {
+	Meters Meters
+}
+
+func (handler *RawHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, connector, ok := handler.Meters.selectConnector(w, r)
+	if !ok {
+		return
+	}
+	frame := connector.LastFrame()
+	if frame == nil {
+		writeError(w, http.StatusServiceUnavailable, "no frame received yet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewRawFrame(name, frame))
+}
+
+// NewFrame builds the values of a standard or historical frame
+func NewFrame(meter string, frame *core.TicFrame) Frame {
+	result := Frame{Meter: meter, ReceivedAt: frame.ReceivedAt}
+	if frame.Standard != nil {
+		result.Mode = "standard"
+		result.Values = frameValues(frame.Standard, frame.Groups, standardUnits)
+	} else if frame.Historical != nil {
+		result.Mode = "historical"
+		result.Values = frameValues(frame.Historical, frame.Groups, historicalUnits)
+	}
+	return result
+}
+
+// Values of the fields decoded from the groups of the frame, zero values included, switching points are
+// served by the schedule instead
+func frameValues(values any, groups []core.TicGroup, units map[string]string) map[string]FrameValue {
+	result := make(map[string]FrameValue)
+	fields := reflect.ValueOf(values).Elem()
+	for _, group := range groups {
+		for _, name := range fieldNames(group.Label) {
+			field := fields.FieldByName(name)
+			if !field.IsValid() || field.Kind() == reflect.Slice {
+				continue
+			}
+			result[core.SnakeCase(name)] = FrameValue{Value: field.Interface(), Unit: units[name]}
+		}
+	}
+	return result
+}
+
+// Names of the fields which may be decoded from a label, like Smaxsnly and SmaxsnlyDate for SMAXSN-1
+func fieldNames(label string) []string {
+	label = strings.ToLower(label)
+	if names, found := labelFields[label]; found {
+		return names
+	}
+	if label == "" {
+		return nil
+	}
+	name := labelSuffixes.Replace(label)
+	name = strings.ToUpper(name[:1]) + name[1:]
+	return []string{name, name + "Date"}
+}
+
+// NewRawFrame builds the raw groups of a frame
+func NewRawFrame(meter string, frame *core.TicFrame) RawFrame {
+	groups := make([]RawGroup, 0, len(frame.Groups))
+	for _, group := range frame.Groups {
+		groups = append(groups, RawGroup(group))
+	}
+	return RawFrame{Meter: meter, ReceivedAt: frame.ReceivedAt, Groups: groups}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestNewFrame(t *testing.T) {
+	// Given
+	values := &core.StandardTicValue{}
+	groups := []core.TicGroup{
+		{Label: "ADSC", Value: "XXXX"},
+		{Label: "SINSTS", Value: "00000"},
+		{Label: "RELAIS", Value: "000"},
+		{Label: "SMAXSN", Horodate: "H240115120000", Value: "03500"},
+	}
+	for _, group := range groups {
+		fields := []string{group.Value, ""}
+		if group.Horodate != "" {
+			fields = []string{group.Horodate, group.Value, ""}
+		}
+		_ = values.ParseParam(group.Label, fields)
+	}
+
+	// When
+	body, err := json.Marshal(NewFrame("house", &core.TicFrame{Standard: values, Groups: groups}))
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var frame map[string]any
+	_ = json.Unmarshal(body, &frame)
+	got := frame["values"].(map[string]any)
+	if frame["mode"] != "standard" || frame["meter"] != "house" || len(got) != 12 {
+		t.Fatalf("got %s", body)
+	}
+	if sinsts := got["sinsts"].(map[string]any); sinsts["value"] != 0.0 || sinsts["unit"] != "VA" {
+		t.Errorf("got sinsts %v", sinsts)
+	}
+	if relai := got["relai1"].(map[string]any); relai["value"] != 0.0 {
+		t.Errorf("got relai1 %v", relai)
+	}
+	if date := got["smaxsn_date"].(map[string]any); date["value"] != "2024-01-15T12:00:00+01:00" {
+		t.Errorf("got smaxsn_date %v", date)
+	}
+	if east, found := got["east"]; found {
+		t.Errorf("got east %v, want it omitted when not sent", east)
+	}
+}
+
+func TestRawHandler(t *testing.T) {
+	// Given
+	handler := RawHandler{Meters: Meters{"house": &core.LinkyConnector{}}}
+	recorder := httptest.NewRecorder()
+
+	// When
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/raw", nil))
+
+	// Then
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", recorder.Code, http.StatusServiceUnavailable)
+	}
+}
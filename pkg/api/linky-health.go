@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// HealthHandler answers as long as the process is alive
+type HealthHandler struct{}
+
+func (handler *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadinessHandler answers successfully when every meter has sent a valid frame for less than MaxAge
+type ReadinessHandler struct {
+	Meters Meters
+	MaxAge time.Duration
+}
+
+// Readiness is the readiness response, with the state of each meter
+type Readiness struct {
+	Status string          `json:"status"`
+	Meters map[string]bool `json:"meters"`
+}
+
+func (handler *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	readiness := Readiness{Status: "ok", Meters: make(map[string]bool)}
+	status := http.StatusOK
+	for name, connector := range handler.Meters {
+		ready := connector.ReceivedWithin(handler.MaxAge)
+		readiness.Meters[name] = ready
+		if !ready {
+			readiness.Status = "no recent frame"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, status, readiness)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Start a connector and wait for its first frame
+func receivingConnector(t *testing.T) *core.LinkyConnector {
+	connector := &core.LinkyConnector{Mode: core.Historical, Device: serveFrames(t)}
+	connector.Start()
+	t.Cleanup(connector.Stop)
+	for connector.LastFrame() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return connector
+}
+
+func TestHealthHandler(t *testing.T) {
+	// Given
+	handler := HealthHandler{}
+	recorder := httptest.NewRecorder()
+
+	// When
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// Then
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Errorf("got %d %q", recorder.Code, recorder.Body)
+	}
+}
+
+func TestReadinessHandlerTableDriven(t *testing.T) {
+	// Given
+	stale := receivingConnector(t)
+	stale.Stop()
+	time.Sleep(100 * time.Millisecond)
+	var tests = []struct {
+		name      string
+		connector *core.LinkyConnector
+		maxAge    time.Duration
+		code      int
+		status    string
+	}{
+		{"recent frame", receivingConnector(t), time.Minute, http.StatusOK, "ok"},
+		{"stale frame", stale, 50 * time.Millisecond, http.StatusServiceUnavailable, "no recent frame"},
+		{"no frame", &core.LinkyConnector{}, time.Minute, http.StatusServiceUnavailable, "no recent frame"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ReadinessHandler{Meters: Meters{"house": tt.connector}, MaxAge: tt.maxAge}
+			recorder := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			// Then
+			var readiness Readiness
+			_ = json.Unmarshal(recorder.Body.Bytes(), &readiness)
+			if recorder.Code != tt.code || readiness.Status != tt.status || readiness.Meters["house"] != (tt.code == http.StatusOK) {
+				t.Errorf("got %d %s", recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Meters are the connectors served by the API, by meter name
+type Meters map[string]*core.LinkyConnector
+
+// Select the meter name and connector of the meter query parameter, optional when there is a single meter.
+// An error response is written if no connector matches.
+func (meters Meters) selectConnector(w http.ResponseWriter, r *http.Request) (string, *core.LinkyConnector, bool) {
+	name := r.URL.Query().Get("meter")
+	if name == "" {
+		if len(meters) != 1 {
+			writeError(w, http.StatusBadRequest, "meter query parameter is required")
+			return "", nil, false
+		}
+		for name, connector := range meters {
+			return name, connector, true
+		}
+	}
+
+	connector, found := meters[name]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown meter %s", name))
+		return "", nil, false
+	}
+	return name, connector, true
+}
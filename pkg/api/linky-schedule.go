@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Dry contact actions names
+var dryContactActions = []string{"unchanged", "tempo", "open", "closed"}
+
+// Schedule is tomorrow's supplier calendar decoded from PJOURF+1 and PPOINTE
+type Schedule struct {
+	LinkyId      string           `json:"linky_id"`
+	Date         string           `json:"date"`
+	Day          int8             `json:"day"`
+	Switches     []ScheduleSwitch `json:"switches"`
+	PeakSwitches []ScheduleSwitch `json:"peak_day_switches"` // Applied on the next peak day, not always tomorrow
+}
+
+// ScheduleSwitch is a switching point with its tariff index and relay commands
+type ScheduleSwitch struct {
+	Time         string    `json:"time"`
+	Start        time.Time `json:"start,omitzero"`
+	Index        uint8     `json:"index"`
+	ClosedRelays []int     `json:"virtual_relays_closed"`
+	DryContact   string    `json:"dry_contact"`
+	Action       string    `json:"action"`
+}
+
+// ScheduleHandler serves tomorrow's schedule of a standard mode meter
+type ScheduleHandler struct {
+	Meters Meters
+}
+
+func (handler *ScheduleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, connector, ok := handler.Meters.selectConnector(w, r)
+	if !ok {
+		return
+	}
+	frame := connector.LastFrame()
+	if frame == nil {
+		writeError(w, http.StatusServiceUnavailable, "no frame received yet")
+		return
+	}
+	if frame.Standard == nil {
+		writeError(w, http.StatusNotFound, "schedule is only sent in standard mode")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSchedule(frame))
+}
+
+// NewSchedule builds tomorrow's schedule from the meter date, or the reception time if not sent
+func NewSchedule(frame *core.TicFrame) Schedule {
+	values := frame.Standard
+	today := frame.ReceivedAt
+	if !values.Date.IsZero() {
+		today = values.Date.Time
+	}
+	tomorrow := core.InMeterTimeZone(today).AddDate(0, 0, 1)
+
+	return Schedule{
+		LinkyId:      values.Adsc,
+		Date:         tomorrow.Format(time.DateOnly),
+		Day:          values.Njourfnd,
+		Switches:     newScheduleSwitches(values.NextDayProfile, tomorrow),
+		PeakSwitches: newScheduleSwitches(values.PeakDayProfile, time.Time{}),
+	}
+}
+
+// Decode switching points, with their start time if the day is known
+func newScheduleSwitches(profile []core.DaySwitch, date time.Time) []ScheduleSwitch {
+	switches := make([]ScheduleSwitch, 0, len(profile))
+	for _, daySwitch := range profile {
+		closedRelays := []int{}
+		for relay := 0; relay < 7; relay++ {
+			if daySwitch.VirtualRelays>>relay&1 == 1 {
+				closedRelays = append(closedRelays, relay+1)
+			}
+		}
+		scheduleSwitch := ScheduleSwitch{
+			Time:         fmt.Sprintf("%02d:%02d", daySwitch.Hour, daySwitch.Minute),
+			Index:        daySwitch.Index,
+			ClosedRelays: closedRelays,
+			DryContact:   dryContactActions[daySwitch.DryContact],
+			Action:       fmt.Sprintf("%04X", daySwitch.Action),
+		}
+		if !date.IsZero() {
+			scheduleSwitch.Start = daySwitch.On(date)
+		}
+		switches = append(switches, scheduleSwitch)
+	}
+	return switches
+}
+
+// Write a JSON response body
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		slog.Error("Unable to write response", "error", err)
+	}
+}
+
+// Write a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
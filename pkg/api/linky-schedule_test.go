@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestNewSchedule(t *testing.T) {
+	// Given
+	values := &core.StandardTicValue{Adsc: "XXXX", Njourfnd: 3}
+	_ = values.ParseParam("DATE", []string{"H240115225959", "", "D"})
+	_ = values.ParseParam("PJOURF+1", []string{"00004001 0600C012 2200C001 NONUTILE", "9"})
+	_ = values.ParseParam("PPOINTE", []string{"00004003 NONUTILE", "9"})
+
+	// When
+	schedule := NewSchedule(&core.TicFrame{Standard: values, ReceivedAt: time.Now()})
+
+	// Then
+	if schedule.Date != "2024-01-16" || schedule.Day != 3 || len(schedule.Switches) != 3 {
+		t.Fatalf("got %+v", schedule)
+	}
+	morning := schedule.Switches[1]
+	if morning.Time != "06:00" || morning.Index != 2 || morning.DryContact != "closed" ||
+		len(morning.ClosedRelays) != 1 || morning.ClosedRelays[0] != 1 {
+		t.Errorf("got %+v", morning)
+	}
+	if morning.Start.Unix() != time.Date(2024, 1, 16, 5, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("got start %s", morning.Start)
+	}
+	if len(schedule.PeakSwitches) != 1 || !schedule.PeakSwitches[0].Start.IsZero() {
+		t.Errorf("got peak %+v", schedule.PeakSwitches)
+	}
+}
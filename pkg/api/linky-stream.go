@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const (
+	// StreamBuffer is the number of frames waiting for a client before new ones are skipped
+	StreamBuffer = 4
+	// StreamWriteTimeout bounds each write to a client, slower clients are dropped
+	StreamWriteTimeout = 5 * time.Second
+	// StreamKeepAlive is the interval of keep alive messages while no frame is received
+	StreamKeepAlive = 30 * time.Second
+)
+
+// Upgrades stream requests to WebSocket, only from pages of the same origin
+var upgrader = websocket.Upgrader{}
+
+// StreamHandler pushes every decoded frame of a meter as soon as received, with Server-Sent Events or with
+// WebSocket for upgrade requests. Frames have the format of FrameHandler.
+type StreamHandler struct {
+	Meters Meters
+	Done   <-chan struct{} // Closes the streams when closed, on shutdown
+}
+
+func (handler *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, connector, ok := handler.Meters.selectConnector(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	if websocket.IsWebSocketUpgrade(r) {
+		err = handler.serveWebSocket(w, r, name, connector)
+	} else {
+		err = handler.serveEvents(w, r, name, connector)
+	}
+	if err != nil {
+		slog.Warn("Stream client dropped", "meter", name, "remote", r.RemoteAddr, "error", err)
+	}
+}
+
+// Stream frames as Server-Sent Events, with comments to keep the connection alive
+func (handler *StreamHandler) serveEvents(w http.ResponseWriter, r *http.Request, name string, connector *core.LinkyConnector) error {
+	controller := http.NewResponseController(w)
+	write := func(message string) error {
+		err := controller.SetWriteDeadline(time.Now().Add(StreamWriteTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		_, err = fmt.Fprint(w, message)
+		if err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	err := write(": connected\n\n")
+	if err != nil {
+		return err
+	}
+
+	return handler.stream(r.Context(), name, connector,
+		func(frame Frame) error {
+			body, err := json.Marshal(frame)
+			if err != nil {
+				return err
+			}
+			return write(fmt.Sprintf("event: frame\ndata: %s\n\n", body))
+		},
+		func() error { return write(": keep-alive\n\n") },
+	)
+}
+
+// Stream frames as WebSocket text messages, with pings to keep the connection alive
+func (handler *StreamHandler) serveWebSocket(w http.ResponseWriter, r *http.Request, name string, connector *core.LinkyConnector) error {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the error
+		return nil
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Read until the client closes the connection, to answer control messages
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = handler.stream(ctx, name, connector,
+		func(frame Frame) error {
+			_ = conn.SetWriteDeadline(time.Now().Add(StreamWriteTimeout))
+			return conn.WriteJSON(frame)
+		},
+		func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(StreamWriteTimeout))
+		},
+	)
+	if err == nil && ctx.Err() == nil {
+		message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down")
+		_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(StreamWriteTimeout))
+	}
+	return err
+}
+
+// Send the last frame then every new frame until the client leaves or the handler is done
+func (handler *StreamHandler) stream(ctx context.Context, name string, connector *core.LinkyConnector,
+	send func(Frame) error, keepAlive func() error) error {
+	frames := connector.Subscribe(StreamBuffer)
+	defer connector.Unsubscribe(frames)
+
+	last := connector.LastFrame()
+	if last != nil {
+		err := send(NewFrame(name, last))
+		if err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(StreamKeepAlive)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-handler.Done:
+			return nil
+		case frame, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			if frame == last {
+				continue
+			}
+			err = send(NewFrame(name, frame))
+		case <-ticker.C:
+			err = keepAlive()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Serve historical frames every 20ms until the test ends
+func serveFrames(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			for {
+				if _, err := conn.Write([]byte("\x02\nADCO 021728123456 @\r\nISOUSC 30 9\r\nPAPP 00800 )\r\x03")); err != nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			_ = conn.Close()
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestStreamHandlerSendsFrames(t *testing.T) {
+	// Given
+	connector := &core.LinkyConnector{Mode: core.Historical, Device: serveFrames(t)}
+	connector.Start()
+	defer connector.Stop()
+	server := httptest.NewServer(&StreamHandler{Meters: Meters{"house": connector}})
+	defer server.Close()
+
+	// When
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Then
+	// The second frame of each client is stored after it connected
+	var events []Frame
+	reader := bufio.NewReader(response.Body)
+	for len(events) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("got events %v then %v", events, err)
+		}
+		if data, found := strings.CutPrefix(line, "data: "); found {
+			var frame Frame
+			_ = json.Unmarshal([]byte(data), &frame)
+			events = append(events, frame)
+		}
+	}
+	var messages []Frame
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(messages) < 2 {
+		var frame Frame
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("got messages %v then %v", messages, err)
+		}
+		messages = append(messages, frame)
+	}
+	for _, frame := range append(events, messages...) {
+		if frame.Meter != "house" || frame.Mode != "historical" || frame.Values["papp"].Value != 800.0 {
+			t.Errorf("got frame %+v", frame)
+		}
+	}
+}
+
+func TestStalledClientDoesNotBlockStore(t *testing.T) {
+	// Given
+	connector := &core.LinkyConnector{Mode: core.Historical, Device: serveFrames(t)}
+	connector.Start()
+	defer connector.Stop()
+	handler := StreamHandler{Meters: Meters{"house": connector}}
+	ctx, cancel := context.WithCancel(context.Background())
+	stalled := make(chan struct{})
+	defer close(stalled)
+	defer cancel()
+
+	// When
+	go func() {
+		_ = handler.stream(ctx, "house", connector,
+			func(Frame) error { <-stalled; return nil },
+			func() error { return nil },
+		)
+	}()
+	frames := connector.Subscribe(1)
+	defer connector.Unsubscribe(frames)
+
+	// Then
+	timeout := time.After(5 * time.Second)
+	for connector.Stats().Frames() < 2*StreamBuffer+2 {
+		select {
+		case <-frames:
+		case <-timeout:
+			t.Fatalf("got %d frames, want reception to go on while a client is stalled", connector.Stats().Frames())
+		}
+	}
+}
+
+func TestStreamHandlerClosesOnDone(t *testing.T) {
+	// Given
+	done := make(chan struct{})
+	server := httptest.NewServer(&StreamHandler{Meters: Meters{"house": &core.LinkyConnector{}}, Done: done})
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// When
+	close(done)
+
+	// Then
+	body, _ := io.ReadAll(response.Body)
+	if response.Header.Get("Content-Type") != "text/event-stream" || string(body) != ": connected\n\n" {
+		t.Errorf("got %s %q", response.Header.Get("Content-Type"), body)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("got %v, want going away close", err)
+	}
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/influx"
+	"github.com/syberalexis/linky-exporter/pkg/mqtt"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+	"github.com/syberalexis/linky-exporter/pkg/remote"
+	"gopkg.in/yaml.v3"
+)
+
+// TIC modes of a device
+const (
+	ModeAuto       = "auto"
+	ModeHistorical = "historical"
+	ModeStandard   = "standard"
+)
+
+// Default configuration values
+const (
+	DefaultAddress = "0.0.0.0"
+	DefaultPort    = 9901
+)
+
+// Config is the exporter configuration, loaded from a YAML file and overridden by flags
+type Config struct {
+	Debug       bool              `yaml:"debug"`
+	Address     string            `yaml:"address"`
+	Port        int               `yaml:"port"`
+	ReadyMaxAge time.Duration     `yaml:"ready_max_age"` // Maximum age of the last frame of each meter for /readyz to succeed
+	Web         WebConfig         `yaml:"web"`
+	Devices     []DeviceConfig    `yaml:"devices"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	API         APIConfig         `yaml:"api"`
+	Messages    MessagesConfig    `yaml:"messages"`
+	MQTT        MQTTConfig        `yaml:"mqtt"`
+	InfluxDB    InfluxDBConfig    `yaml:"influxdb"`
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+}
+
+// DeviceConfig describes a meter, serial parameters use the mode defaults when empty
+type DeviceConfig struct {
+	Name      string `yaml:"name"`   // Value of the meter label, the device when empty
+	Device    string `yaml:"device"` // Serial device path, tcp://host:port or file:///path/capture.tic
+	Mode      string `yaml:"mode"`   // ModeAuto, ModeHistorical or ModeStandard
+	BaudRate  int    `yaml:"baud"`
+	FrameSize int    `yaml:"size"`
+	Parity    string `yaml:"parity"`
+	StopBits  string `yaml:"stopbits"`
+}
+
+// WebConfig configures the HTTP listeners, TLS and authentication
+type WebConfig struct {
+	ListenAddresses []string `yaml:"listen_addresses"` // Addresses to listen on, address and port if empty
+	SystemdSocket   bool     `yaml:"systemd_socket"`   // Use systemd socket activation listeners
+	ConfigFile      string   `yaml:"config_file"`      // exporter-toolkit web configuration file
+}
+
+// MetricsConfig selects the exported metrics
+type MetricsConfig struct {
+	Groups   []string `yaml:"groups"`   // Enabled metric groups like energy or power, all when empty
+	Disabled []string `yaml:"disabled"` // Names of metrics not to export, like linky_status, even in an enabled group
+}
+
+// APIConfig enables the JSON API served next to metrics
+type APIConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// MessagesConfig configures meter message notifications
+type MessagesConfig struct {
+	Webhook string `yaml:"webhook"` // URL receiving a JSON POST on MSG1 or MSG2 change
+}
+
+// MQTTConfig configures the MQTT publisher with Home Assistant discovery
+type MQTTConfig struct {
+	Broker          string `yaml:"broker"` // Broker URL like tcp://localhost:1883, publisher disabled if empty
+	ClientId        string `yaml:"client_id"`
+	Username        string `yaml:"username"`
+	Password        string `yaml:"password"`
+	TopicPrefix     string `yaml:"topic_prefix"`
+	DiscoveryPrefix string `yaml:"discovery_prefix"` // Home Assistant discovery disabled if empty
+}
+
+// InfluxDBConfig configures the InfluxDB v2 line protocol output
+type InfluxDBConfig struct {
+	URL           string        `yaml:"url"` // Base URL like http://localhost:8086, output disabled if empty
+	Org           string        `yaml:"org"`
+	Bucket        string        `yaml:"bucket"`
+	Token         string        `yaml:"token"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	SpoolDir      string        `yaml:"spool_dir"`      // Directory keeping batches while the server is unreachable
+	SpoolMaxSize  int64         `yaml:"spool_max_size"` // Maximum size in bytes of the spool directory
+}
+
+// RemoteWriteConfig configures the Prometheus remote write push
+type RemoteWriteConfig struct {
+	URL         string        `yaml:"url"` // Receiver URL like http://prometheus:9090/api/v1/write, push disabled if empty
+	Username    string        `yaml:"username"`
+	Password    string        `yaml:"password"`
+	BearerToken string        `yaml:"bearer_token"`
+	Interval    time.Duration `yaml:"interval"`
+	WALDir      string        `yaml:"wal_dir"`      // Directory keeping requests across outages and restarts, in memory if empty
+	WALMaxSize  int64         `yaml:"wal_max_size"` // Maximum size in bytes of the WAL directory
+}
+
+// Default returns the configuration used without file
+func Default() Config {
+	return Config{
+		Address:     DefaultAddress,
+		Port:        DefaultPort,
+		ReadyMaxAge: core.FrameTimeout,
+		API:         APIConfig{Enabled: true},
+		MQTT: MQTTConfig{
+			ClientId:        "linky-exporter",
+			TopicPrefix:     mqtt.DefaultTopicPrefix,
+			DiscoveryPrefix: mqtt.DefaultDiscoveryPrefix,
+		},
+		InfluxDB: InfluxDBConfig{
+			BatchSize:     influx.DefaultBatchSize,
+			FlushInterval: influx.DefaultFlushInterval,
+			SpoolMaxSize:  influx.DefaultSpoolMaxSize,
+		},
+		RemoteWrite: RemoteWriteConfig{Interval: remote.DefaultInterval, WALMaxSize: remote.DefaultWALMaxSize},
+	}
+}
+
+// Load reads a YAML configuration file over the default configuration, unknown fields are errors
+func Load(path string) (Config, error) {
+	config := Default()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer func() { _ = file.Close() }()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(&config)
+	if err != nil {
+		return config, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Validate checks the configuration and returns all errors found
+func (config *Config) Validate() error {
+	var errs []error
+
+	if config.Port < 1 || config.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port: %d is not between 1 and 65535", config.Port))
+	}
+	err := web.Validate(config.Web.ConfigFile)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("web.config_file: %w", err))
+	}
+	if config.MQTT.Broker != "" {
+		broker, err := url.Parse(config.MQTT.Broker)
+		if err != nil || broker.Scheme == "" || broker.Host == "" {
+			errs = append(errs, fmt.Errorf("mqtt.broker: %q is not an URL like tcp://localhost:1883", config.MQTT.Broker))
+		}
+		if config.MQTT.TopicPrefix == "" {
+			errs = append(errs, errors.New("mqtt.topic_prefix: is required"))
+		}
+	}
+	if config.InfluxDB.URL != "" {
+		influxURL, err := url.Parse(config.InfluxDB.URL)
+		if err != nil || (influxURL.Scheme != "http" && influxURL.Scheme != "https") || influxURL.Host == "" {
+			errs = append(errs, fmt.Errorf("influxdb.url: %q is not an URL like http://localhost:8086", config.InfluxDB.URL))
+		}
+		if config.InfluxDB.Bucket == "" {
+			errs = append(errs, errors.New("influxdb.bucket: is required"))
+		}
+		if config.InfluxDB.BatchSize <= 0 {
+			errs = append(errs, fmt.Errorf("influxdb.batch_size: %d is not positive", config.InfluxDB.BatchSize))
+		}
+		if config.InfluxDB.FlushInterval <= 0 {
+			errs = append(errs, fmt.Errorf("influxdb.flush_interval: %s is not positive", config.InfluxDB.FlushInterval))
+		}
+		if config.InfluxDB.SpoolMaxSize <= 0 {
+			errs = append(errs, fmt.Errorf("influxdb.spool_max_size: %d is not positive", config.InfluxDB.SpoolMaxSize))
+		}
+	}
+	if config.RemoteWrite.URL != "" {
+		remoteURL, err := url.Parse(config.RemoteWrite.URL)
+		if err != nil || (remoteURL.Scheme != "http" && remoteURL.Scheme != "https") || remoteURL.Host == "" {
+			errs = append(errs, fmt.Errorf("remote_write.url: %q is not an URL like http://prometheus:9090/api/v1/write", config.RemoteWrite.URL))
+		}
+		if config.RemoteWrite.Interval <= 0 {
+			errs = append(errs, fmt.Errorf("remote_write.interval: %s is not positive", config.RemoteWrite.Interval))
+		}
+		if config.RemoteWrite.WALMaxSize <= 0 {
+			errs = append(errs, fmt.Errorf("remote_write.wal_max_size: %d is not positive", config.RemoteWrite.WALMaxSize))
+		}
+	}
+	if _, err := prom.DisabledByGroups(config.Metrics.Groups); err != nil {
+		errs = append(errs, fmt.Errorf("metrics.groups: %w", err))
+	}
+	if config.ReadyMaxAge <= 0 {
+		errs = append(errs, fmt.Errorf("ready_max_age: %s is not positive", config.ReadyMaxAge))
+	}
+	if len(config.Devices) == 0 {
+		errs = append(errs, errors.New("devices: at least one device is required"))
+	}
+	names := make(map[string]int)
+	for i, device := range config.Devices {
+		if device.Device == "" {
+			errs = append(errs, fmt.Errorf("devices[%d].device: is required", i))
+		}
+		if first, found := names[device.MeterName()]; found {
+			errs = append(errs, fmt.Errorf("devices[%d].name: %q is already used by devices[%d]", i, device.MeterName(), first))
+		} else {
+			names[device.MeterName()] = i
+		}
+		switch device.Mode {
+		case "", ModeAuto:
+			// Detection tries the default serial parameters of each mode
+			if device.BaudRate != 0 || device.FrameSize != 0 || device.Parity != "" || device.StopBits != "" {
+				errs = append(errs, fmt.Errorf("devices[%d]: baud, size, parity and stopbits need mode historical or standard", i))
+			}
+		case ModeHistorical, ModeStandard:
+		default:
+			errs = append(errs, fmt.Errorf("devices[%d].mode: %q is not auto, historical or standard", i, device.Mode))
+		}
+		if device.BaudRate < 0 {
+			errs = append(errs, fmt.Errorf("devices[%d].baud: %d is negative", i, device.BaudRate))
+		}
+		if device.FrameSize != 0 && (device.FrameSize < 5 || device.FrameSize > 8) {
+			errs = append(errs, fmt.Errorf("devices[%d].size: %d is not between 5 and 8", i, device.FrameSize))
+		}
+		if device.Parity != "" {
+			if _, err := core.ParseParity(device.Parity); err != nil {
+				errs = append(errs, fmt.Errorf("devices[%d].parity: %w", i, err))
+			}
+		}
+		if device.StopBits != "" {
+			if _, err := core.ParseStopBits(device.StopBits); err != nil {
+				errs = append(errs, fmt.Errorf("devices[%d].stopbits: %w", i, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DisabledMetrics returns the metrics not to export, disabled by name or by group
+func (metrics *MetricsConfig) DisabledMetrics() []string {
+	disabled, _ := prom.DisabledByGroups(metrics.Groups)
+	disabled = append(disabled, metrics.Disabled...)
+	slices.Sort(disabled)
+	return slices.Compact(disabled)
+}
+
+// MeterName returns the name identifying the meter in metrics and API
+func (device *DeviceConfig) MeterName() string {
+	if device.Name != "" {
+		return device.Name
+	}
+	return device.Device
+}
+
+// Connector builds the connector of the device, detection is needed in auto mode
+func (device *DeviceConfig) Connector() (connector *core.LinkyConnector, detect bool, err error) {
+	connector = &core.LinkyConnector{Device: device.Device}
+
+	var mode core.LinkyMode
+	switch device.Mode {
+	case ModeStandard:
+		mode = core.Standard
+	case ModeHistorical:
+		mode = core.Historical
+	default:
+		return connector, true, nil
+	}
+
+	serialMode, err := core.ParseSerialMode(mode, device.BaudRate, device.FrameSize, device.Parity, device.StopBits)
+	if err != nil {
+		return nil, false, fmt.Errorf("device %s: %w", device.Device, err)
+	}
+	connector.Mode = mode
+	connector.BaudRate = serialMode.BaudRate
+	connector.FrameSize = serialMode.DataBits
+	connector.Parity = serialMode.Parity
+	connector.StopBits = serialMode.StopBits
+
+	return connector, false, nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "linky.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	// Given
+	path := writeConfig(t, `
+port: 9902
+ready_max_age: 1m
+devices:
+  - device: /dev/ttyUSB0
+    mode: standard
+    parity: E
+metrics:
+  groups: [energy, status]
+  disabled: [linky_status]
+`)
+
+	// When
+	config, err := Load(path)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if config.Port != 9902 || config.Address != DefaultAddress || !config.API.Enabled || config.ReadyMaxAge != time.Minute {
+		t.Errorf("got %+v", config)
+	}
+	if len(config.Devices) != 1 || config.Devices[0].Mode != ModeStandard || config.Devices[0].Parity != "E" {
+		t.Errorf("got %+v", config.Devices)
+	}
+	disabled := config.Metrics.DisabledMetrics()
+	if !slices.Contains(disabled, "linky_status") || !slices.Contains(disabled, "linky_power") ||
+		slices.Contains(disabled, "linky_energy") || slices.Contains(disabled, "linky_relay") {
+		t.Errorf("got %v", disabled)
+	}
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	// Given
+	path := writeConfig(t, "devices:\n  - device: /dev/ttyUSB0\n    baudrate: 9600\n")
+
+	// When
+	_, err := Load(path)
+
+	// Then
+	if err == nil || !strings.Contains(err.Error(), "baudrate") {
+		t.Errorf("got %v, want unknown field error", err)
+	}
+}
+
+func TestValidateTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name   string
+		config Config
+		errors []string
+	}{
+		{"valid", Config{Port: 9901, ReadyMaxAge: time.Minute, Devices: []DeviceConfig{{Device: "/dev/ttyUSB0"}}}, nil},
+		{"no device", Config{Port: 9901, ReadyMaxAge: time.Minute}, []string{"devices: at least one device is required"}},
+		{"several devices", Config{Port: 9901, ReadyMaxAge: time.Minute, Devices: []DeviceConfig{
+			{Name: "house", Device: "/dev/ttyUSB0"},
+			{Name: "workshop", Device: "/dev/ttyUSB1"},
+		}}, nil},
+		{"missing web config", Config{Port: 9901, ReadyMaxAge: time.Minute, Web: WebConfig{ConfigFile: "/nonexistent/web.yml"}, Devices: []DeviceConfig{
+			{Device: "/dev/ttyUSB0"},
+		}}, []string{"web.config_file: open /nonexistent/web.yml: no such file or directory"}},
+		{"same name", Config{Port: 9901, ReadyMaxAge: time.Minute, Devices: []DeviceConfig{
+			{Device: "/dev/ttyUSB0"},
+			{Name: "/dev/ttyUSB0", Device: "/dev/ttyUSB1"},
+		}}, []string{`devices[1].name: "/dev/ttyUSB0" is already used by devices[0]`}},
+		{"serial parameters in auto mode", Config{Port: 9901, ReadyMaxAge: time.Minute, Devices: []DeviceConfig{
+			{Device: "/dev/ttyUSB0", Mode: ModeAuto, Parity: "E"},
+		}}, []string{"devices[0]: baud, size, parity and stopbits need mode historical or standard"}},
+		{"unknown metric group", Config{Port: 9901, ReadyMaxAge: time.Minute, Metrics: MetricsConfig{Groups: []string{"energy", "tic"}},
+			Devices: []DeviceConfig{{Device: "/dev/ttyUSB0"}},
+		}, []string{`metrics.groups: unknown metric group "tic", must be one of electrical, energy, exporter, load_curve, power, status, tariff`}},
+		{"invalid", Config{Port: 0, Devices: []DeviceConfig{{Mode: "tic", BaudRate: -1, Parity: "X"}}}, []string{
+			"port: 0 is not between 1 and 65535",
+			"ready_max_age: 0s is not positive",
+			"devices[0].device: is required",
+			`devices[0].mode: "tic" is not auto, historical or standard`,
+			"devices[0].baud: -1 is negative",
+			`devices[0].parity: invalid parity "X", must be N, O, E, M or S`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			err := tt.config.Validate()
+
+			// Then
+			if tt.errors == nil {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != strings.Join(tt.errors, "\n") {
+				t.Errorf("got %v, want %v", err, tt.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ASCII separators used inside information groups
+const (
+	SP = 0x20 // Space - separates historical group fields
+	HT = 0x09 // Horizontal Tab - separates standard group fields
+)
+
+// Labels not matching this pattern are considered as garbage
+var groupLabelRegex = regexp.MustCompile(`^[A-Z0-9+\-]{1,10}$`)
+
+// ChecksumError describes an information group with an invalid checksum
+type ChecksumError struct {
+	Label    string // Group label, "invalid" when unreadable
+	Expected byte   // Checksum computed from the group
+	Got      byte   // Checksum sent by the meter
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("invalid checksum for %s: expected %q, got %q", e.Label, e.Expected, e.Got)
+}
+
+// Compute Enedis checksum : sum of characters, truncated to 6 bits, plus 0x20
+func checksum(data string) byte {
+	var sum uint
+	for i := 0; i < len(data); i++ {
+		sum += uint(data[i])
+	}
+	return byte(sum&0x3F) + 0x20
+}
+
+// Check and split one information group (without LF and CR) into label, values and checksum.
+// Historical checksum excludes the separator before it, standard checksum includes it.
+func splitGroup(mode LinkyMode, group string) ([]string, error) {
+	separator := byte(SP)
+	if mode == Standard {
+		separator = HT
+	}
+
+	if len(group) < 4 || group[len(group)-2] != separator {
+		return nil, fmt.Errorf("malformed group %q", group)
+	}
+
+	got := group[len(group)-1]
+	data := group[:len(group)-2]
+	var covered string
+	var fields []string
+	if mode == Standard {
+		// Standard values may contain spaces and be preceded by a horodate
+		covered = group[:len(group)-1]
+		fields = strings.Split(data, string(separator))
+	} else {
+		covered = data
+		fields = strings.SplitN(data, string(separator), 2)
+	}
+
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("malformed group %q", group)
+	}
+
+	if expected := checksum(covered); expected != got {
+		label := fields[0]
+		if !groupLabelRegex.MatchString(label) {
+			label = "invalid"
+		}
+		return nil, &ChecksumError{Label: label, Expected: expected, Got: got}
+	}
+
+	return append(fields, string(got)), nil
+}
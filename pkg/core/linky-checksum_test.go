@@ -0,0 +1,73 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplitGroupTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		mode  LinkyMode
+		group string
+		want  []string
+	}{
+		{Historical, "ADCO 021728123456 @", []string{"ADCO", "021728123456", "@"}},
+		{Historical, "ISOUSC 30 9", []string{"ISOUSC", "30", "9"}},
+		{Standard, "ADSC\t041876097812\tB", []string{"ADSC", "041876097812", "B"}},
+		{Standard, "DATE\tH221113153547\t\tD", []string{"DATE", "H221113153547", "", "D"}},
+		{Standard, "SMAXSN\tH221113002750\t01750\t2", []string{"SMAXSN", "H221113002750", "01750", "2"}},
+		{Standard, "MSG1\tPAS DE          MESSAGE         \t<", []string{"MSG1", "PAS DE          MESSAGE         ", "<"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			// When
+			got, err := splitGroup(tt.mode, tt.group)
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitGroupInvalidTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		mode          LinkyMode
+		group         string
+		checksumLabel string
+	}{
+		{Historical, "PAPP 00750 .", "PAPP"},
+		{Historical, "P\x7fPP 00750 -", "invalid"},
+		{Historical, "ADSC\t041876097812\tB", ""},
+		{Standard, "ADCO 021728123456 @", ""},
+		{Standard, "ADSC\t041876097813\tB", "ADSC"},
+		{Standard, "A\tB", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			// When
+			_, err := splitGroup(tt.mode, tt.group)
+
+			// Then
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var checksumErr *ChecksumError
+			if errors.As(err, &checksumErr) != (tt.checksumLabel != "") {
+				t.Fatalf("unexpected error type %v", err)
+			}
+			if checksumErr != nil && checksumErr.Label != tt.checksumLabel {
+				t.Errorf("got label %s, want %s", checksumErr.Label, tt.checksumLabel)
+			}
+		})
+	}
+}
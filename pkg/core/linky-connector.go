@@ -2,30 +2,40 @@ package core
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"os"
-	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"go.bug.st/serial"
 )
 
 type LinkyConnector struct {
-	Mode      LinkyMode
-	Device    string
-	BaudRate  int
-	FrameSize int
-	Parity    serial.Parity
-	StopBits  serial.StopBits
+	Mode       LinkyMode
+	Device     string
+	BaudRate   int
+	FrameSize  int
+	Parity     serial.Parity
+	StopBits   serial.StopBits
+	AutoDetect bool // Detect the mode in background before reading frames, retried after each failure
+
+	cache  TicCache
+	stats  LinkyStats
+	mutex  sync.Mutex
+	stream io.ReadCloser
+	done   chan struct{}
 }
 
 // Detect serial connection mode
 func (connector *LinkyConnector) Detect() error {
-	slog.Info("Trying to auto detect TIC mode...")
+	slog.Info("Trying to auto detect TIC mode...", "device", connector.Device)
 
 	if connector.trySerial(Standard) {
-		slog.Info("Standard Mode detected !")
+		slog.Info("Standard Mode detected !", "device", connector.Device)
 		connector.Mode = Standard
 		connector.BaudRate = Standard.BaudRate
 		connector.FrameSize = Standard.FrameSize
@@ -37,7 +47,7 @@ func (connector *LinkyConnector) Detect() error {
 	}
 
 	if connector.trySerial(Historical) {
-		slog.Info("Historical Mode detected !")
+		slog.Info("Historical Mode detected !", "device", connector.Device)
 		connector.Mode = Historical
 		connector.BaudRate = Historical.BaudRate
 		connector.FrameSize = Historical.FrameSize
@@ -51,26 +61,35 @@ func (connector *LinkyConnector) Detect() error {
 	return fmt.Errorf("impossible to auto detect TIC mode ")
 }
 
-// Try serial connection and reading
+// Try to read valid groups of the mode from the device
 func (connector *LinkyConnector) trySerial(mode LinkyMode) bool {
-	m := &serial.Mode{BaudRate: mode.BaudRate, DataBits: mode.FrameSize, Parity: mode.Parity, StopBits: mode.StopBits}
-	stream, err := serial.Open(connector.Device, m)
+	source, err := NewSource(connector.Device, &serial.Mode{BaudRate: mode.BaudRate, DataBits: mode.FrameSize, Parity: mode.Parity, StopBits: mode.StopBits})
 	if err != nil {
+		slog.Debug("Invalid device", "error", err)
 		return false
 	}
+	stream, err := source.Open()
+	if err != nil {
+		slog.Debug("Unable to open device", "error", err)
+		return false
+	}
+	defer func() { _ = stream.Close() }()
 
 	reader := bufio.NewReader(stream)
-	regex := regexp.MustCompile(`^[A-Z0-9\-+]+ +[a-zA-Z0-9 .\-]+ +.$`)
 
 	slog.Debug("Read serial data...")
 	for i := 1; i <= 5; i++ {
-		bytes, _, _ := reader.ReadLine()
-		line := string(bytes)
+		bytes, _, err := reader.ReadLine()
+		if err != nil {
+			slog.Debug("Unable to read device", "error", err)
+			return false
+		}
+		line := strings.Trim(string(bytes), "\x02\x03\x04\r")
 		slog.Debug("Try line", "number", i, "total", 5, "content", line)
-		if regex.MatchString(line) {
+		if _, err := splitGroup(mode, line); err == nil {
 			return true
 		} else {
-			slog.Debug("Regex not match")
+			slog.Debug("Invalid group", "error", err)
 		}
 	}
 	return false
@@ -80,134 +99,361 @@ func (connector *LinkyConnector) trySerial(mode LinkyMode) bool {
 const (
 	STX = 0x02 // Start of Text - marks the beginning of a data block
 	ETX = 0x03 // End of Text - marks the end of a data block
+	EOT = 0x04 // End of Transmission - marks an interrupted data block
+	LF  = 0x0A // Line Feed - marks the beginning of an information group
+	CR  = 0x0D // Carriage Return - marks the end of an information group
 )
 
-// readSerial values
-func (connector *LinkyConnector) readSerial() ([][]string, error) {
-	slog.Debug("Read serial with config",
+const (
+	// Initial delay before reopening the device after a failure, doubled on each new failure
+	MinReconnectDelay = 1 * time.Second
+	// Maximum delay before reopening the device after a failure
+	MaxReconnectDelay = 1 * time.Minute
+	// Maximum age of the last frame to consider the meter up
+	FrameTimeout = 30 * time.Second
+)
+
+// Start continuously reads TIC frames in background until Stop is called
+func (connector *LinkyConnector) Start() {
+	connector.done = make(chan struct{})
+	go connector.listen()
+}
+
+// Stop ends background reading and closes the device
+func (connector *LinkyConnector) Stop() {
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
+	if connector.done == nil || connector.stopped() {
+		return
+	}
+	close(connector.done)
+	if connector.stream != nil {
+		err := connector.stream.Close()
+		if err != nil {
+			slog.Error("Failed to close device", "error", err)
+		}
+		connector.stream = nil
+	}
+}
+
+// LastFrame returns the last decoded frame, nil if none has been received yet
+func (connector *LinkyConnector) LastFrame() *TicFrame {
+	return connector.cache.Load()
+}
+
+// Subscribe returns a channel receiving each new decoded frame, frames are dropped while its buffer is full
+func (connector *LinkyConnector) Subscribe(size int) <-chan *TicFrame {
+	return connector.cache.Subscribe(size)
+}
+
+// Unsubscribe stops sending frames to a channel returned by Subscribe and closes it
+func (connector *LinkyConnector) Unsubscribe(subscription <-chan *TicFrame) {
+	connector.cache.Unsubscribe(subscription)
+}
+
+// Stats returns the connector counters
+func (connector *LinkyConnector) Stats() *LinkyStats {
+	return &connector.stats
+}
+
+// Keep reading frames, reopening the device after each failure
+func (connector *LinkyConnector) listen() {
+	delay := MinReconnectDelay
+	for {
+		frames := connector.stats.Frames()
+		err := connector.detect()
+		if err == nil {
+			err = connector.readFrames()
+		}
+		if connector.stopped() {
+			return
+		}
+
+		// Reset backoff once the device has been working
+		if connector.stats.Frames() > frames {
+			delay = MinReconnectDelay
+		}
+		slog.Error("Failed to read device", "device", connector.Device, "error", err, "retry", delay)
+
+		select {
+		case <-connector.done:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, MaxReconnectDelay)
+	}
+}
+
+// Detect the mode if requested and not detected yet
+func (connector *LinkyConnector) detect() error {
+	if !connector.AutoDetect || connector.Mode != (LinkyMode{}) {
+		return nil
+	}
+	err := connector.Detect()
+	if err != nil && !connector.stopped() {
+		connector.stats.addFrameError(ReasonDetect)
+	}
+	return err
+}
+
+// Check if Stop has been called
+func (connector *LinkyConnector) stopped() bool {
+	select {
+	case <-connector.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Open device and publish every decoded frame until an error occurs
+func (connector *LinkyConnector) readFrames() error {
+	stream, err := connector.open()
+	if err != nil {
+		connector.stats.addFrameError(ReasonOpen)
+		return err
+	}
+	defer connector.close(stream)
+
+	reader := bufio.NewReader(stream)
+	for {
+		lines, err := connector.readSerial(reader)
+		if err != nil {
+			if !connector.stopped() {
+				connector.stats.addFrameError(ReasonRead)
+			}
+			return err
+		}
+		connector.cache.Store(connector.decode(lines))
+	}
+}
+
+// Open device with connector configuration
+func (connector *LinkyConnector) open() (io.ReadCloser, error) {
+	slog.Debug("Open device with config",
 		"device", connector.Device,
 		"baudrate", connector.BaudRate,
 		"framesize", connector.FrameSize,
 		"parity", connector.Parity,
 		"stopbits", connector.StopBits)
 	m := &serial.Mode{BaudRate: connector.BaudRate, DataBits: connector.FrameSize, Parity: connector.Parity, StopBits: connector.StopBits}
-	stream, err := serial.Open(connector.Device, m)
+	source, err := NewSource(connector.Device, m)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := source.Open()
 	if err != nil {
 		return nil, err
 	}
 
-	reader := bufio.NewReader(stream)
-	started := false
-	var values [][]string
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+	if connector.stopped() {
+		_ = stream.Close()
+		return nil, fmt.Errorf("connector stopped")
+	}
+	connector.stream = stream
+	return stream, nil
+}
 
-	slog.Debug("Read serial data...")
+// Close device unless Stop already did it
+func (connector *LinkyConnector) close(stream io.ReadCloser) {
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+	if connector.stream != stream {
+		return
+	}
+	err := stream.Close()
+	if err != nil {
+		slog.Error("Failed to close device", "error", err)
+	}
+	connector.stream = nil
+}
+
+// readSerial values of the next complete frame
+func (connector *LinkyConnector) readSerial(reader *bufio.Reader) ([][]string, error) {
 	for {
-		bytes, _, err := reader.ReadLine()
+		// Skip data until a frame starts
+		_, err := reader.ReadBytes(STX)
 		if err != nil {
 			return nil, err
 		}
 
-		line := string(bytes)
+		start := time.Now()
+		block, err := reader.ReadBytes(ETX)
+		if err != nil {
+			return nil, err
+		}
 
-		// End loop when block ended
-		if started && strings.ContainsRune(line, ETX) {
-			err = stream.Close()
-			if err != nil {
-				slog.Error("Failed to close serial", "error", err)
-			}
-			break
+		// Keep only the last started frame and drop interrupted ones
+		block = block[bytes.LastIndexByte(block, STX)+1 : len(block)-1]
+		if bytes.IndexByte(block, EOT) >= 0 {
+			slog.Debug("Interrupted frame ignored")
+			connector.stats.addFrameError(ReasonInterrupted)
+			continue
 		}
 
-		// Collect data line by line
-		if started {
+		var values [][]string
+		lines := strings.FieldsFunc(string(block), func(r rune) bool { return r == LF || r == CR })
+		for _, line := range lines {
 			slog.Debug(line)
-			fields := strings.FieldsFunc(line, func(r rune) bool { return r == 0x09 || r == ' ' })
-			if len(fields) > 0 {
-				values = append(values, fields)
+			fields, err := splitGroup(connector.Mode, line)
+			if err != nil {
+				var checksumErr *ChecksumError
+				if errors.As(err, &checksumErr) {
+					connector.stats.addChecksumError(checksumErr.Label)
+				} else {
+					connector.stats.addFrameError(ReasonMalformed)
+				}
+				slog.Debug("Invalid group ignored", "error", err)
+				continue
 			}
+			values = append(values, fields)
 		}
+		slog.Debug("Read serial frame ended !")
+		connector.stats.addFrame(time.Since(start))
 
-		// Start reading data when block started
-		if strings.ContainsRune(line, STX) {
-			started = true
-		}
+		return values, nil
 	}
-	slog.Debug("Read serial data ended !")
-
-	return values, nil
 }
 
-// GetLastHistoricalTicValue return last serial Historical TIC
-func (connector *LinkyConnector) GetLastHistoricalTicValue() (*HistoricalTicValue, error) {
-	lines, err := connector.readSerial()
+// Decode frame values depending on the connector mode
+func (connector *LinkyConnector) decode(lines [][]string) *TicFrame {
+	frame := &TicFrame{ReceivedAt: time.Now(), Groups: make([]TicGroup, 0, len(lines))}
+	for _, line := range lines {
+		frame.Groups = append(frame.Groups, newTicGroup(line))
+	}
 
-	if err != nil {
-		slog.Error("Failed to read historical serial", "error", err)
-		return nil, err
+	var parser interface {
+		ParseParam(name string, values []string) error
+	}
+	switch connector.Mode {
+	case Standard:
+		frame.Standard = &StandardTicValue{}
+		parser = frame.Standard
+	case Historical:
+		frame.Historical = &HistoricalTicValue{}
+		parser = frame.Historical
+	default:
+		return frame
 	}
 
-	values := HistoricalTicValue{}
 	for _, line := range lines {
-		values.ParseParam(line[0], line[1:])
+		err := parser.ParseParam(line[0], line[1:])
+		if errors.Is(err, ErrUnknownLabel) {
+			connector.stats.addUnknownLabel(line[0])
+		} else if err != nil {
+			slog.Debug("Invalid group value ignored", "error", err)
+			connector.stats.addFrameError(ReasonParse)
+		}
 	}
 
-	return &values, nil
+	return frame
 }
 
-// GetLastStandardTicValue return last serial Standard TIC
-func (connector *LinkyConnector) GetLastStandardTicValue() (*StandardTicValue, error) {
-	lines, err := connector.readSerial()
-
-	if err != nil {
-		slog.Error("Failed to read standard serial", "error", err)
-		return nil, err
+// Build a raw group from the fields split by splitGroup: label, optional horodate, value and checksum
+func newTicGroup(fields []string) TicGroup {
+	group := TicGroup{Label: fields[0], Value: fields[len(fields)-2], Checksum: fields[len(fields)-1]}
+	if len(fields) > 3 {
+		group.Horodate = fields[1]
 	}
+	return group
+}
 
-	values := StandardTicValue{}
-	for _, line := range lines {
-		values.ParseParam(line[0], line[1:])
+// IsUp checks if a frame has been received recently
+func (connector *LinkyConnector) IsUp() bool {
+	return connector.ReceivedWithin(FrameTimeout)
+}
+
+// ReceivedWithin checks if a frame has been received for less than maxAge
+func (connector *LinkyConnector) ReceivedWithin(maxAge time.Duration) bool {
+	frame := connector.cache.Load()
+	return frame != nil && time.Since(frame.ReceivedAt) < maxAge
+}
+
+// GetLastHistoricalTicValue return last received Historical TIC
+func (connector *LinkyConnector) GetLastHistoricalTicValue() (*HistoricalTicValue, error) {
+	frame := connector.cache.Load()
+	if frame == nil || frame.Historical == nil {
+		return nil, fmt.Errorf("no historical frame received yet")
 	}
+	return frame.Historical, nil
+}
 
-	return &values, nil
+// GetLastStandardTicValue return last received Standard TIC
+func (connector *LinkyConnector) GetLastStandardTicValue() (*StandardTicValue, error) {
+	frame := connector.cache.Load()
+	if frame == nil || frame.Standard == nil {
+		return nil, fmt.Errorf("no standard frame received yet")
+	}
+	return frame.Standard, nil
 }
 
 // ParseParity from string to serial object
-func ParseParity(value string) (parity serial.Parity) {
+func ParseParity(value string) (serial.Parity, error) {
 	switch value {
 	case "ParityNone", "N":
-		parity = serial.NoParity
-
+		return serial.NoParity, nil
 	case "ParityOdd", "O":
-		parity = serial.OddParity
-
+		return serial.OddParity, nil
 	case "ParityEven", "E":
-		parity = serial.EvenParity
-
+		return serial.EvenParity, nil
 	case "ParityMark", "M":
-		parity = serial.MarkParity
-
+		return serial.MarkParity, nil
 	case "ParitySpace", "S":
-		parity = serial.SpaceParity
-
+		return serial.SpaceParity, nil
 	default:
-		slog.Error("Impossible to parse Parity", "error", value)
-		os.Exit(3)
+		return serial.NoParity, fmt.Errorf("invalid parity %q, must be N, O, E, M or S", value)
 	}
-	return
 }
 
 // ParseStopBits from string to serial object
-func ParseStopBits(value string) (stopBits serial.StopBits) {
+func ParseStopBits(value string) (serial.StopBits, error) {
 	switch value {
 	case "Stop1", "1":
-		stopBits = serial.OneStopBit
+		return serial.OneStopBit, nil
 	case "Stop1Half", "15":
-		stopBits = serial.OnePointFiveStopBits
+		return serial.OnePointFiveStopBits, nil
 	case "Stop2", "2":
-		stopBits = serial.TwoStopBits
+		return serial.TwoStopBits, nil
+	default:
+		return serial.OneStopBit, fmt.Errorf("invalid stop bits %q, must be 1, 15 or 2", value)
+	}
+}
+
+// ParseSerialMode builds the serial configuration of a mode, overridden by the non zero values given
+func ParseSerialMode(mode LinkyMode, baudRate, frameSize int, parity, stopBits string) (*serial.Mode, error) {
+	serialMode := &serial.Mode{BaudRate: mode.BaudRate, DataBits: mode.FrameSize, Parity: mode.Parity, StopBits: mode.StopBits}
+	var errs []error
+
+	switch {
+	case baudRate < 0:
+		errs = append(errs, fmt.Errorf("invalid baud rate %d", baudRate))
+	case baudRate > 0:
+		serialMode.BaudRate = baudRate
+	}
+	switch {
+	case frameSize == 0:
+	case frameSize < 5 || frameSize > 8:
+		errs = append(errs, fmt.Errorf("invalid frame size %d, must be between 5 and 8", frameSize))
 	default:
-		slog.Error("Impossible to parse StopBits", "value", value)
-		os.Exit(3)
+		serialMode.DataBits = frameSize
+	}
+	if parity != "" {
+		var err error
+		serialMode.Parity, err = ParseParity(parity)
+		errs = append(errs, err)
+	}
+	if stopBits != "" {
+		var err error
+		serialMode.StopBits, err = ParseStopBits(stopBits)
+		errs = append(errs, err)
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return serialMode, nil
 }
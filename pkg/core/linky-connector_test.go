@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"go.bug.st/serial"
+)
+
+func TestReadSerialFrames(t *testing.T) {
+	// Given
+	stream := "PAPP 00750 -\r\x03" +
+		"\x02\nADCO 021728123456 @\r\nPAPP 00750 -\r\x04" +
+		"\x02\nADCO 021728123456 @\r\nISOUSC 30 9\r\nIINST 002 !\r\nPAPP 00800 )\r\x03" +
+		"\x02\nADCO 021728123456 @\r"
+	reader := bufio.NewReader(strings.NewReader(stream))
+	connector := LinkyConnector{Mode: Historical}
+
+	// When
+	lines, err := connector.readSerial(reader)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d groups, want 3", len(lines))
+	}
+	if got := connector.Stats().ChecksumErrors()["IINST"]; got != 1 {
+		t.Errorf("got %d checksum errors, want 1", got)
+	}
+	if lines[2][0] != "PAPP" || lines[2][1] != "00800" {
+		t.Errorf("got %v, want last group PAPP 00800", lines[2])
+	}
+
+	// When
+	_, err = connector.readSerial(reader)
+
+	// Then
+	if err == nil {
+		t.Error("incomplete frame must return an error")
+	}
+}
+
+func TestDecodeHistoricalFrame(t *testing.T) {
+	// Given
+	connector := LinkyConnector{Mode: Historical}
+	lines := [][]string{{"ADCO", "021728123456", "@"}, {"PAPP", "00800", ")"}, {"ADIR1", "031", "X"}, {"IINST", "0#2", "!"}}
+
+	// When
+	frame := connector.decode(lines)
+
+	// Then
+	if frame.Standard != nil || frame.Historical == nil {
+		t.Fatal("historical mode must decode historical values")
+	}
+	if frame.Historical.Adco != "021728123456" || frame.Historical.Papp != 800 {
+		t.Errorf("got %+v", frame.Historical)
+	}
+	if frame.ReceivedAt.IsZero() {
+		t.Error("reception time must be set")
+	}
+	if got := connector.Stats().UnknownLabels()["ADIR1"]; got != 1 {
+		t.Errorf("got %d unknown labels, want 1", got)
+	}
+	if got := connector.Stats().FrameErrors()[ReasonParse]; got != 1 {
+		t.Errorf("got %d parse errors, want 1", got)
+	}
+	if len(frame.Groups) != 4 || frame.Groups[1] != (TicGroup{Label: "PAPP", Value: "00800", Checksum: ")"}) {
+		t.Errorf("got groups %+v", frame.Groups)
+	}
+}
+
+func TestParseSerialModeTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name      string
+		mode      LinkyMode
+		baudRate  int
+		frameSize int
+		parity    string
+		stopBits  string
+		expected  *serial.Mode
+		errors    int
+	}{
+		{"mode defaults", Historical, 0, 0, "", "", &serial.Mode{BaudRate: 1200, DataBits: 7, Parity: serial.NoParity, StopBits: serial.OneStopBit}, 0},
+		{"overrides", Standard, 19200, 8, "E", "Stop2", &serial.Mode{BaudRate: 19200, DataBits: 8, Parity: serial.EvenParity, StopBits: serial.TwoStopBits}, 0},
+		{"long names", Standard, 0, 0, "ParityOdd", "15", &serial.Mode{BaudRate: 9600, DataBits: 7, Parity: serial.OddParity, StopBits: serial.OnePointFiveStopBits}, 0},
+		{"invalid parity", Standard, 0, 0, "X", "", nil, 1},
+		{"all invalid", Standard, -1, 9, "X", "3", nil, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			mode, err := ParseSerialMode(tt.mode, tt.baudRate, tt.frameSize, tt.parity, tt.stopBits)
+
+			// Then
+			if tt.errors == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if *mode != *tt.expected {
+					t.Errorf("got %+v, want %+v", *mode, *tt.expected)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got %+v, want an error", *mode)
+			}
+			if got := len(err.(interface{ Unwrap() []error }).Unwrap()); got != tt.errors {
+				t.Errorf("got %d errors, want %d: %v", got, tt.errors, err)
+			}
+		})
+	}
+}
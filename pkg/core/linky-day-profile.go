@@ -0,0 +1,90 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DayProfileSize is the number of switching points in a day profile
+const DayProfileSize = 11
+
+// Unused switching point in a day profile
+const unusedSwitch = "NONUTILE"
+
+// Dry contact actions of a switching point
+const (
+	DryContactUnchanged = 0
+	DryContactTempo     = 1
+	DryContactOpen      = 2
+	DryContactClosed    = 3
+)
+
+// DaySwitch is a switching point of a supplier calendar day profile (PJOURF+1, PPOINTE)
+type DaySwitch struct {
+	Hour          uint8
+	Minute        uint8
+	Action        uint16 // Raw SSSS action word
+	Index         uint8  // Supplier tariff index from the switching time, 0 if unchanged
+	VirtualRelays uint8  // Virtual relays 1 to 7 states, bit 0 for relay 1
+	DryContact    uint8  // Dry contact action, DryContactUnchanged, DryContactTempo, DryContactOpen or DryContactClosed
+}
+
+// Minutes returns the switching time in minutes after midnight
+func (daySwitch DaySwitch) Minutes() int {
+	return int(daySwitch.Hour)*60 + int(daySwitch.Minute)
+}
+
+// On returns the switching time on the day of the date, in the meters time zone
+func (daySwitch DaySwitch) On(date time.Time) time.Time {
+	year, month, day := date.In(paris).Date()
+	return time.Date(year, month, day, int(daySwitch.Hour), int(daySwitch.Minute), 0, 0, paris)
+}
+
+// String returns the switching point as sent by the meter, HHMMSSSS
+func (daySwitch DaySwitch) String() string {
+	return fmt.Sprintf("%02d%02d%04X", daySwitch.Hour, daySwitch.Minute, daySwitch.Action)
+}
+
+// ParseDayProfile decodes the used switching points of a day profile made of up to 11 HHMMSSSS or NONUTILE blocks
+func ParseDayProfile(value string) ([]DaySwitch, error) {
+	blocks := strings.Fields(value)
+	if len(blocks) > DayProfileSize {
+		return nil, fmt.Errorf("invalid day profile %q: %d switching points", value, len(blocks))
+	}
+
+	var switches []DaySwitch
+	for _, block := range blocks {
+		if block == unusedSwitch {
+			continue
+		}
+		if len(block) != 8 {
+			return nil, fmt.Errorf("invalid day profile switching point %q", block)
+		}
+
+		hour, err := strconv.ParseUint(block[0:2], 10, 8)
+		if err != nil || hour > 23 {
+			return nil, fmt.Errorf("invalid day profile switching hour %q", block)
+		}
+		minute, err := strconv.ParseUint(block[2:4], 10, 8)
+		if err != nil || minute > 59 {
+			return nil, fmt.Errorf("invalid day profile switching minute %q", block)
+		}
+		action, err := strconv.ParseUint(block[4:8], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid day profile switching action %q", block)
+		}
+
+		switches = append(switches, DaySwitch{
+			Hour:          uint8(hour),
+			Minute:        uint8(minute),
+			Action:        uint16(action),
+			Index:         uint8(action & 0x0F),
+			VirtualRelays: uint8(action >> 4 & 0x7F),
+			DryContact:    uint8(action >> 14 & 0x03),
+		})
+	}
+
+	return switches, nil
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseDayProfileTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		value string
+		want  []DaySwitch
+		err   bool
+	}{
+		{"00008001 NONUTILE NONUTILE NONUTILE NONUTILE NONUTILE NONUTILE NONUTILE NONUTILE NONUTILE NONUTILE",
+			[]DaySwitch{{0, 0, 0x8001, 1, 0, DryContactOpen}}, false},
+		{"00004001 0630C012 2200C031",
+			[]DaySwitch{{0, 0, 0x4001, 1, 0, DryContactTempo}, {6, 30, 0xC012, 2, 1, DryContactClosed}, {22, 0, 0xC031, 1, 3, DryContactClosed}}, false},
+		{"NONUTILE", nil, false},
+		{"2500C001", nil, true},
+		{"0000800", nil, true},
+		{"0000XXXX", nil, true},
+		{"00008001 00008001 00008001 00008001 00008001 00008001 00008001 00008001 00008001 00008001 00008001 00008001", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			// When
+			got, err := ParseDayProfile(tt.value)
+
+			// Then
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %t", err, tt.err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %+v, want %+v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SnakeCase converts a Go field name like CPLSyncStatus to cpl_sync_status
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteByte('_')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Bits sent on the line for each TIC character : start, 7 data, parity and stop bits
+const bitsPerCharacter = 10
+
+// FileSource replays TIC raw bytes recorded in a capture file
+type FileSource struct {
+	Path     string
+	BaudRate int  // Pace the replay like a serial line at this rate, as fast as possible if 0
+	Loop     bool // Restart from the beginning at end of file instead of waiting
+}
+
+// Build file source from an URL like file:///path/capture.tic?pace=fast&loop=false
+func newFileSource(fileUrl *url.URL, mode *serial.Mode) (*FileSource, error) {
+	if fileUrl.Path == "" {
+		return nil, fmt.Errorf("missing path in device URL %s", fileUrl)
+	}
+	source := &FileSource{Path: fileUrl.Path, BaudRate: mode.BaudRate, Loop: true}
+
+	query := fileUrl.Query()
+	switch query.Get("pace") {
+	case "", "realtime":
+	case "fast":
+		source.BaudRate = 0
+	default:
+		return nil, fmt.Errorf("invalid pace %s, must be realtime or fast", query.Get("pace"))
+	}
+	if value := query.Get("loop"); value != "" {
+		loop, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loop %s: %w", value, err)
+		}
+		source.Loop = loop
+	}
+
+	return source, nil
+}
+
+// Open capture file
+func (source *FileSource) Open() (io.ReadCloser, error) {
+	file, err := os.Open(source.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStream{source: source, file: file, start: time.Now(), closed: make(chan struct{})}, nil
+}
+
+func (source *FileSource) String() string {
+	return "file://" + source.Path
+}
+
+// Capture file stream, paced and looping depending on its source
+type fileStream struct {
+	source    *FileSource
+	file      *os.File
+	start     time.Time
+	sent      int64
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (stream *fileStream) Read(p []byte) (int, error) {
+	// Read at most 100ms of data at a time to keep a smooth pace
+	bytesPerSecond := int64(stream.source.BaudRate / bitsPerCharacter)
+	if bytesPerSecond > 0 && int64(len(p)) > bytesPerSecond/10+1 {
+		p = p[:bytesPerSecond/10+1]
+	}
+
+	n, err := stream.file.Read(p)
+	if errors.Is(err, io.EOF) && n == 0 {
+		if !stream.source.Loop {
+			// Keep the stream open without data until closed
+			<-stream.closed
+			return 0, os.ErrClosed
+		}
+		if _, err := stream.file.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		n, err = stream.file.Read(p)
+		if errors.Is(err, io.EOF) && n == 0 {
+			return 0, fmt.Errorf("empty capture file %s", stream.source.Path)
+		}
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	if bytesPerSecond > 0 {
+		stream.sent += int64(n)
+		due := stream.start.Add(time.Duration(stream.sent * int64(time.Second) / bytesPerSecond))
+		select {
+		case <-stream.closed:
+			return 0, os.ErrClosed
+		case <-time.After(time.Until(due)):
+		}
+	}
+	return n, nil
+}
+
+func (stream *fileStream) Close() error {
+	stream.closeOnce.Do(func() { close(stream.closed) })
+	return stream.file.Close()
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -14,7 +16,7 @@ type HistoricalTicValue struct {
 	Hchc     int32  // Index option Heures creuses : Heures Creuses en Wh
 	Hchp     int32  // Index option Heures pleines : Heures Pleines en Wh
 	Ejphn    int32  // Index option EJP : Heures Normales en Wh
-	Ejphpn   int32  // Index option EJP : Heures de Pointe Mobile en Wh
+	Ejphpn   int32  // Index option EJP : Heures de Pointe Mobile en Wh (EJPHPM)
 	Bbrhcjb  int32  // Index option Tempo : Heures Creuses Jours Bleus en Wh
 	Bbrhpjb  int32  // Index option Tempo : Heures Pleines Jours Bleus en Wh
 	Bbrhcjw  int32  // Index option Tempo : Heures Creuses Jours Blancs en Wh
@@ -37,101 +39,151 @@ type HistoricalTicValue struct {
 	Papp     int32  // Puissance Apparente en VA
 	Hhphc    string // Horaire Heures Pleines Heures Creuses
 	Motdetat string // Mot d'état du compteur
-	Ppot     string // potentiels is here
+	Ppot     string // Présence des potentiels
+
+	StateWord       uint32 // Mot d'état du compteur décodé, 24 bits
+	PhasePotential1 uint8  // Présence du potentiel phase 1 (1 présent, 0 absent)
+	PhasePotential2 uint8  // Présence du potentiel phase 2 (1 présent, 0 absent)
+	PhasePotential3 uint8  // Présence du potentiel phase 3 (1 présent, 0 absent)
+	ThreePhase      bool   // Compteur triphasé, détecté par les groupes envoyés par phase
+}
+
+// Labels only sent by three-phase meters, even without consumption
+var threePhaseLabels = map[string]bool{
+	"iinst1": true, "iinst2": true, "iinst3": true,
+	"imax1": true, "imax2": true, "imax3": true,
+	"pmax": true, "ppot": true,
 }
 
 // Parse parameter with name and value
-func (tic *HistoricalTicValue) ParseParam(name string, values []string) {
+func (tic *HistoricalTicValue) ParseParam(name string, values []string) error {
 	if len(values) == 0 {
-		return
+		return nil
 	}
 
-	switch strings.ToLower(name) {
+	var err error
+	number := func(value string, base int, bitSize int) int64 {
+		val, parseErr := parseInt(name, value, base, bitSize)
+		if err == nil {
+			err = parseErr
+		}
+		return val
+	}
+	label := strings.ToLower(name)
+	if threePhaseLabels[label] {
+		tic.ThreePhase = true
+	}
+	switch label {
 	case "adco":
 		tic.Adco = values[0]
 	case "optarif":
 		tic.Optarif = values[0]
 	case "isousc":
-		val, _ := strconv.ParseUint(values[0], 10, 8)
+		val := number(values[0], 10, 8)
 		tic.Isousc = uint8(val)
 	case "base":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Base = int32(val)
 	case "hchc":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Hchc = int32(val)
 	case "hchp":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Hchp = int32(val)
 	case "ejphn":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Ejphn = int32(val)
-	case "ejphpn":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+	case "ejphpm", "ejphpn":
+		val := number(values[0], 10, 32)
 		tic.Ejphpn = int32(val)
 	case "bbrhcjb":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Bbrhcjb = int32(val)
 	case "bbrhpjb":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Bbrhpjb = int32(val)
 	case "bbrhcjw":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Bbrhcjw = int32(val)
 	case "bbrhpjw":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Bbrhpjw = int32(val)
 	case "bbrhcjr":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Bbrhcjr = int32(val)
 	case "bbrhpjr":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Bbrhpjr = int32(val)
 	case "pejp":
-		val, _ := strconv.ParseInt(values[0], 10, 8)
+		val := number(values[0], 10, 8)
 		tic.Pejp = int8(val)
 	case "ptec":
 		tic.Ptec = values[0]
 	case "demain":
 		tic.Demain = values[0]
 	case "iinst":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Iinst = int16(val)
 	case "iinst1":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Iinst1 = int16(val)
 	case "iinst2":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Iinst2 = int16(val)
 	case "iinst3":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Iinst3 = int16(val)
 	case "adps":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Adps = int16(val)
 	case "imax":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Imax = int16(val)
 	case "imax1":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Imax1 = int16(val)
 	case "imax2":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Imax2 = int16(val)
 	case "imax3":
-		val, _ := strconv.ParseInt(values[0], 10, 16)
+		val := number(values[0], 10, 16)
 		tic.Imax3 = int16(val)
 	case "pmax":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Pmax = int32(val)
 	case "papp":
-		val, _ := strconv.ParseInt(values[0], 10, 32)
+		val := number(values[0], 10, 32)
 		tic.Papp = int32(val)
 	case "hhphc":
 		tic.Hhphc = values[0]
 	case "motdetat":
 		tic.Motdetat = strings.Join(values[:len(values)-1], " ")
+		val, err := strconv.ParseUint(tic.Motdetat, 16, 24)
+		if err != nil {
+			return fmt.Errorf("invalid MOTDETAT %q: %w", tic.Motdetat, err)
+		}
+		tic.StateWord = uint32(val)
 	case "ppot":
 		tic.Ppot = values[0]
+		val, err := strconv.ParseUint(tic.Ppot, 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid PPOT %q: %w", tic.Ppot, err)
+		}
+		// Bit n set when the potential of phase n is missing, bit 0 is not significant
+		tic.PhasePotential1 = uint8(^val>>1) & 1
+		tic.PhasePotential2 = uint8(^val>>2) & 1
+		tic.PhasePotential3 = uint8(^val>>3) & 1
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownLabel, name)
+	}
+	return err
+}
+
+// Parse a signed number of a group, values out of range are capped
+func parseInt(name string, value string, base int, bitSize int) (int64, error) {
+	val, err := strconv.ParseInt(value, base, bitSize)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%w for %s: %q", ErrInvalidValue, name, value)
 	}
+	return val, nil
 }
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseParamTableDrivenPpot(t *testing.T) {
+	// Given
+	var tests = []struct {
+		value               string
+		want1, want2, want3 uint8
+		err                 bool
+	}{
+		{"00", 1, 1, 1, false},
+		{"01", 1, 1, 1, false},
+		{"02", 0, 1, 1, false},
+		{"0C", 1, 0, 0, false},
+		{"0E", 0, 0, 0, false},
+		{"ZZ", 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			tic := HistoricalTicValue{}
+
+			// When
+			err := tic.ParseParam("PPOT", []string{tt.value, "#"})
+
+			// Then
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %t", err, tt.err)
+			}
+			if tic.PhasePotential1 != tt.want1 || tic.PhasePotential2 != tt.want2 || tic.PhasePotential3 != tt.want3 {
+				t.Errorf("got %d %d %d, want %d %d %d", tic.PhasePotential1, tic.PhasePotential2, tic.PhasePotential3,
+					tt.want1, tt.want2, tt.want3)
+			}
+		})
+	}
+}
+
+func TestParseParamMotdetat(t *testing.T) {
+	// Given
+	tic := HistoricalTicValue{}
+
+	// When
+	err := tic.ParseParam("MOTDETAT", []string{"000A01", "B"})
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tic.StateWord != 0x000A01 {
+		t.Errorf("got %#x, want 0xa01", tic.StateWord)
+	}
+}
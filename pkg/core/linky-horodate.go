@@ -0,0 +1,84 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Meters always use french time
+)
+
+// ErrInvalidHorodate is returned when a standard mode horodate can't be parsed
+var ErrInvalidHorodate = errors.New("invalid horodate")
+
+// Time zone of the meters
+var paris, _ = time.LoadLocation("Europe/Paris")
+
+// InMeterTimeZone returns the date in the french time zone used by the meters
+func InMeterTimeZone(date time.Time) time.Time {
+	return date.In(paris)
+}
+
+// Season markers of an horodate, lowercase when the meter clock is degraded
+const (
+	SeasonWinter  = 'H' // UTC+1
+	SeasonSummer  = 'E' // UTC+2
+	SeasonUnknown = ' ' // Meter without season management, french local time
+)
+
+const horodateLayout = "060102150405"
+
+// Horodate is the date attached to standard mode groups, like H221113153547
+type Horodate struct {
+	time.Time
+	Season   byte // SeasonWinter, SeasonSummer or SeasonUnknown
+	Degraded bool // Lowercase season, the meter clock has lost its synchronization
+}
+
+// NewHorodate builds the horodate a synchronized meter sends for a date
+func NewHorodate(date time.Time) Horodate {
+	date = date.In(paris)
+	season := byte(SeasonWinter)
+	if date.IsDST() {
+		season = SeasonSummer
+	}
+	return Horodate{Time: date, Season: season}
+}
+
+// ParseHorodate parses the season marker followed by YYMMDDhhmmss
+func ParseHorodate(value string) (Horodate, error) {
+	if len(value) != len(horodateLayout)+1 {
+		return Horodate{}, fmt.Errorf("%w: %q has not %d characters", ErrInvalidHorodate, value, len(horodateLayout)+1)
+	}
+
+	horodate := Horodate{Season: strings.ToUpper(value[:1])[0], Degraded: value[0] == 'h' || value[0] == 'e'}
+
+	var location *time.Location
+	switch horodate.Season {
+	case SeasonWinter:
+		location = time.FixedZone("CET", 3600)
+	case SeasonSummer:
+		location = time.FixedZone("CEST", 7200)
+	case SeasonUnknown:
+		location = paris
+	default:
+		return Horodate{}, fmt.Errorf("%w: unknown season %q in %q", ErrInvalidHorodate, value[0], value)
+	}
+
+	date, err := time.ParseInLocation(horodateLayout, value[1:], location)
+	if err != nil {
+		return Horodate{}, fmt.Errorf("%w: %w", ErrInvalidHorodate, err)
+	}
+	horodate.Time = date
+
+	return horodate, nil
+}
+
+// String formats the horodate as sent by the meter
+func (horodate Horodate) String() string {
+	season := string(horodate.Season)
+	if horodate.Degraded {
+		season = strings.ToLower(season)
+	}
+	return season + horodate.Format(horodateLayout)
+}
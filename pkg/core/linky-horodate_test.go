@@ -0,0 +1,77 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseHorodateTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		value    string
+		want     int64
+		season   byte
+		degraded bool
+	}{
+		{"H221113153547", 1668350147, SeasonWinter, false},
+		{"h221113153547", 1668350147, SeasonWinter, true},
+		{"E220714153000", 1657805400, SeasonSummer, false},
+		{"e220714153000", 1657805400, SeasonSummer, true},
+		{" 220714153000", 1657805400, SeasonUnknown, false},
+		{" 221113153547", 1668350147, SeasonUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			// When
+			horodate, err := ParseHorodate(tt.value)
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if horodate.Unix() != tt.want || horodate.Season != tt.season || horodate.Degraded != tt.degraded {
+				t.Errorf("got %d %q %t, want %d %q %t", horodate.Unix(), horodate.Season, horodate.Degraded,
+					tt.want, tt.season, tt.degraded)
+			}
+			if horodate.String() != tt.value {
+				t.Errorf("got %q, want %q", horodate.String(), tt.value)
+			}
+		})
+	}
+}
+
+func TestParseHorodateInvalidTableDriven(t *testing.T) {
+	// Given
+	var tests = []string{"", "H", "H2211131535", "X221113153547", "H221313153547", "H22111315354A"}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			// When
+			_, err := ParseHorodate(value)
+
+			// Then
+			if !errors.Is(err, ErrInvalidHorodate) {
+				t.Errorf("got %v, want ErrInvalidHorodate", err)
+			}
+		})
+	}
+}
+
+func TestNewHorodateSeason(t *testing.T) {
+	// Given
+	winter := time.Date(2022, 11, 13, 14, 35, 47, 0, time.UTC)
+	summer := time.Date(2022, 7, 14, 13, 30, 0, 0, time.UTC)
+
+	// When
+	winterHorodate, summerHorodate := NewHorodate(winter), NewHorodate(summer)
+
+	// Then
+	if winterHorodate.String() != "H221113153547" {
+		t.Errorf("got %s, want H221113153547", winterHorodate)
+	}
+	if summerHorodate.String() != "E220714153000" {
+		t.Errorf("got %s, want E220714153000", summerHorodate)
+	}
+}
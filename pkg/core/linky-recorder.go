@@ -0,0 +1,83 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Record copies raw bytes read from the device to output until the context ends.
+// When index is not nil, a line "<offset>\t<RFC3339 timestamp>" is written to it for each frame start.
+func (connector *LinkyConnector) Record(ctx context.Context, output io.Writer, index io.Writer) (int64, error) {
+	m := &serial.Mode{BaudRate: connector.BaudRate, DataBits: connector.FrameSize, Parity: connector.Parity, StopBits: connector.StopBits}
+	source, err := NewSource(connector.Device, m)
+	if err != nil {
+		return 0, err
+	}
+	stream, err := source.Open()
+	if err != nil {
+		return 0, err
+	}
+
+	// Unblock reading when the context ends
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	defer func() {
+		if stop() {
+			_ = stream.Close()
+		}
+	}()
+
+	slog.Info("Recording raw TIC data...", "device", source)
+	var written int64
+	buffer := make([]byte, 4096)
+	for {
+		n, err := stream.Read(buffer)
+		if n > 0 {
+			if index != nil {
+				if err := writeIndex(index, buffer[:n], written); err != nil {
+					return written, err
+				}
+			}
+			if _, err := output.Write(buffer[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		if ctx.Err() != nil {
+			return written, nil
+		}
+		// Serial timeouts only mean that the meter is silent
+		if err != nil && !isTimeout(err) {
+			return written, err
+		}
+	}
+}
+
+// Write one index line per frame start found in data
+func writeIndex(index io.Writer, data []byte, offset int64) error {
+	now := time.Now().Format(time.RFC3339Nano)
+	for i := bytes.IndexByte(data, STX); i >= 0; {
+		if _, err := fmt.Fprintf(index, "%d\t%s\n", offset+int64(i), now); err != nil {
+			return err
+		}
+		next := bytes.IndexByte(data[i+1:], STX)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil
+}
+
+// Check if an error is a read timeout
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
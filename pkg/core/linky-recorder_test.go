@@ -0,0 +1,45 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordOverTCP(t *testing.T) {
+	// Given
+	connector := &LinkyConnector{Device: serveFrames(t, 3)}
+	var output, index bytes.Buffer
+	start := time.Now()
+
+	// When
+	written, err := connector.Record(context.Background(), &output, &index)
+
+	// Then
+	if err != nil && !errors.Is(err, io.EOF) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := strings.Repeat(historicalFrame, 3)
+	if output.String() != expected || written != int64(len(expected)) {
+		t.Fatalf("got %d bytes %q, want %q", written, output.String(), expected)
+	}
+	lines := strings.Split(strings.TrimSuffix(index.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got index %q, want 3 lines", index.String())
+	}
+	for i, line := range lines {
+		offset, date, found := strings.Cut(line, "\t")
+		if offset != fmt.Sprint(i*len(historicalFrame)) || !found {
+			t.Errorf("got line %q, want offset %d", line, i*len(historicalFrame))
+		}
+		recorded, err := time.Parse(time.RFC3339Nano, date)
+		if err != nil || recorded.Before(start.Truncate(time.Second)) {
+			t.Errorf("got line %q, want a timestamp after %s", line, start)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// PtyOutput is a pseudo terminal fed by a simulator, read by the exporter like a serial device
+type PtyOutput struct {
+	master *os.File
+	slave  *os.File
+}
+
+// OpenPtyOutput creates a pseudo terminal in raw mode
+func OpenPtyOutput() (*PtyOutput, error) {
+	master, slave, err := pty.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	// Avoid echo and line conversions until the exporter opens the device
+	if _, err := term.MakeRaw(int(slave.Fd())); err != nil {
+		_ = master.Close()
+		_ = slave.Close()
+		return nil, err
+	}
+
+	return &PtyOutput{master: master, slave: slave}, nil
+}
+
+// Device returns the path to give to the exporter
+func (output *PtyOutput) Device() string {
+	return output.slave.Name()
+}
+
+func (output *PtyOutput) Write(p []byte) (int, error) {
+	return output.master.Write(p)
+}
+
+func (output *PtyOutput) Close() error {
+	_ = output.slave.Close()
+	return output.master.Close()
+}
+
+// TCPOutput sends everything written to all connected TCP clients, like a ser2net bridge
+type TCPOutput struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	clients  map[net.Conn]struct{}
+}
+
+// ListenTCPOutput accepts clients in background on the address
+func ListenTCPOutput(address string) (*TCPOutput, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &TCPOutput{listener: listener, clients: make(map[net.Conn]struct{})}
+	go output.accept()
+	return output, nil
+}
+
+// Address returns the listening address
+func (output *TCPOutput) Address() string {
+	return output.listener.Addr().String()
+}
+
+// Accept clients until the listener is closed
+func (output *TCPOutput) accept() {
+	for {
+		conn, err := output.listener.Accept()
+		if err != nil {
+			return
+		}
+		slog.Info("Simulator client connected", "client", conn.RemoteAddr())
+		output.mutex.Lock()
+		output.clients[conn] = struct{}{}
+		output.mutex.Unlock()
+	}
+}
+
+// Write to all clients, disconnecting the failing or too slow ones
+func (output *TCPOutput) Write(p []byte) (int, error) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	for conn := range output.clients {
+		err := conn.SetWriteDeadline(time.Now().Add(FrameTimeout))
+		if err == nil {
+			_, err = conn.Write(p)
+		}
+		if err != nil {
+			slog.Info("Simulator client disconnected", "client", conn.RemoteAddr())
+			_ = conn.Close()
+			delete(output.clients, conn)
+		}
+	}
+	return len(p), nil
+}
+
+func (output *TCPOutput) Close() error {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	for conn := range output.clients {
+		_ = conn.Close()
+	}
+	return output.listener.Close()
+}
+
+var (
+	_ io.WriteCloser = (*PtyOutput)(nil)
+	_ io.WriteCloser = (*TCPOutput)(nil)
+)
@@ -0,0 +1,416 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Simulated contracts
+const (
+	ContractBase = "BASE" // Single index
+	ContractHCHP = "HCHP" // Off-peak hours from 22:00 to 06:00
+	ContractEJP  = "EJP"  // Mobile peak days from 07:00
+	ContractBBR  = "BBR"  // Tempo with blue, white and red days
+)
+
+// Tempo day colors, as encoded in standard mode status
+const (
+	tempoBlue  = 1
+	tempoWhite = 2
+	tempoRed   = 3
+)
+
+// Standard mode supplier calendar names
+var standardCalendars = map[string]string{
+	ContractBase: "BASE",
+	ContractHCHP: "H PLEINE/CREUSE",
+	ContractEJP:  "EJP",
+	ContractBBR:  "TEMPO",
+}
+
+// Standard mode supplier tariff labels per index
+var standardTariffLabels = map[string][]string{
+	ContractBase: {"BASE"},
+	ContractHCHP: {"HEURE CREUSE", "HEURE PLEINE"},
+	ContractEJP:  {"HEURE NORMALE", "HEURE POINTE"},
+	ContractBBR:  {"HC BLEU", "HP BLEU", "HC BLANC", "HP BLANC", "HC ROUGE", "HP ROUGE"},
+}
+
+// LoadPoint is the apparent power used from a time of the day
+type LoadPoint struct {
+	Minute int     // Minutes since midnight
+	Power  float64 // Apparent power in VA
+}
+
+// LoadProfile is a daily apparent power curve, linearly interpolated between its points
+type LoadProfile []LoadPoint
+
+// DefaultLoadProfile looks like a household with electric cooking
+var DefaultLoadProfile = LoadProfile{
+	{0, 350}, {6 * 60, 400}, {7 * 60, 1500}, {9 * 60, 500}, {12 * 60, 2200},
+	{14 * 60, 600}, {18 * 60, 900}, {19*60 + 30, 3200}, {22 * 60, 1200}, {23 * 60, 400},
+}
+
+// ParseLoadProfile parses a profile like "00:00=300,07:00=1500,19:00=3000"
+func ParseLoadProfile(value string) (LoadProfile, error) {
+	var profile LoadProfile
+	for _, point := range strings.Split(value, ",") {
+		clock, power, found := strings.Cut(strings.TrimSpace(point), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid load point %q, expected HH:MM=VA", point)
+		}
+		start, err := time.Parse("15:04", clock)
+		if err != nil {
+			return nil, fmt.Errorf("invalid load point time %q: %w", clock, err)
+		}
+		va, err := strconv.ParseFloat(power, 64)
+		if err != nil || va < 0 {
+			return nil, fmt.Errorf("invalid load point power %q", power)
+		}
+		profile = append(profile, LoadPoint{Minute: start.Hour()*60 + start.Minute(), Power: va})
+	}
+	sort.Slice(profile, func(i, j int) bool { return profile[i].Minute < profile[j].Minute })
+	for i := 1; i < len(profile); i++ {
+		if profile[i].Minute == profile[i-1].Minute {
+			return nil, fmt.Errorf("duplicated load point time in %q", value)
+		}
+	}
+	return profile, nil
+}
+
+// Power returns the apparent power at a time of the day
+func (profile LoadProfile) Power(t time.Time) float64 {
+	if len(profile) == 0 {
+		return 0
+	}
+
+	minute := float64(t.Hour()*60+t.Minute()) + float64(t.Second())/60
+	points := len(profile)
+	for i := 0; i <= points; i++ {
+		previous, next := profile[(i+points-1)%points], profile[i%points]
+		start, end := float64(previous.Minute), float64(next.Minute)
+		// Wrap around midnight
+		if i == 0 {
+			start -= 24 * 60
+		}
+		if i == points {
+			end += 24 * 60
+		}
+		if minute < end {
+			return previous.Power + (minute-start)/(end-start)*(next.Power-previous.Power)
+		}
+	}
+	return profile[points-1].Power
+}
+
+// LinkySimulator generates realistic TIC frames of a meter
+type LinkySimulator struct {
+	Mode     LinkyMode
+	Contract string
+	Profile  LoadProfile
+	Address  string // ADCO or ADSC of the simulated meter
+	Prm      string // PRM of the simulated meter in standard mode
+
+	indexes  [6]float64 // Energy in Wh per tariff index
+	last     time.Time
+	maxPower float64
+	maxTime  time.Time
+	random   *rand.Rand
+}
+
+// NewLinkySimulator creates a simulator with realistic starting indexes
+func NewLinkySimulator(mode LinkyMode, contract string, profile LoadProfile) (*LinkySimulator, error) {
+	if mode != Historical && mode != Standard {
+		return nil, fmt.Errorf("unknown simulated mode")
+	}
+	switch contract {
+	case ContractBase, ContractHCHP, ContractEJP, ContractBBR:
+	default:
+		return nil, fmt.Errorf("unknown simulated contract %s, must be BASE, HCHP, EJP or BBR", contract)
+	}
+
+	simulator := &LinkySimulator{
+		Mode:     mode,
+		Contract: contract,
+		Profile:  profile,
+		Address:  "021728123456",
+		Prm:      "16140520874326",
+		random:   rand.New(rand.NewPCG(1, 2)),
+	}
+	for i := range simulator.indexes {
+		simulator.indexes[i] = float64(12345678 / (i + 1))
+	}
+	return simulator, nil
+}
+
+// Run writes one frame per interval to output until the context ends
+func (simulator *LinkySimulator) Run(ctx context.Context, output io.Writer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := output.Write(simulator.Frame(time.Now())); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Frame builds the frame sent at the given time, evolving indexes since the previous one
+func (simulator *LinkySimulator) Frame(now time.Time) []byte {
+	now = now.In(paris)
+	power := simulator.Profile.Power(now) * (0.95 + 0.1*simulator.random.Float64())
+	index := simulator.tariffIndex(now)
+	if !simulator.last.IsZero() {
+		simulator.indexes[index] += power * now.Sub(simulator.last).Hours()
+	}
+	simulator.last = now
+	if power > simulator.maxPower || now.YearDay() != simulator.maxTime.YearDay() {
+		simulator.maxPower = power
+		simulator.maxTime = now
+	}
+
+	frame := &bytes.Buffer{}
+	frame.WriteByte(STX)
+	if simulator.Mode == Standard {
+		simulator.writeStandardGroups(frame, now, power, index)
+	} else {
+		simulator.writeHistoricalGroups(frame, now, power, index)
+	}
+	frame.WriteByte(ETX)
+	return frame.Bytes()
+}
+
+// Index in use at the given time, following the contract
+func (simulator *LinkySimulator) tariffIndex(now time.Time) int {
+	offPeak := now.Hour() >= 22 || now.Hour() < 6
+	switch simulator.Contract {
+	case ContractHCHP:
+		if offPeak {
+			return 0
+		}
+		return 1
+	case ContractEJP:
+		if isEJPDay(now) && now.Hour() >= 7 {
+			return 1
+		}
+		return 0
+	case ContractBBR:
+		color := tempoColor(tempoDay(now))
+		if offPeak {
+			return (color - 1) * 2
+		}
+		return (color-1)*2 + 1
+	default:
+		return 0
+	}
+}
+
+// Tempo days begin at 06:00
+func tempoDay(now time.Time) time.Time {
+	if now.Hour() < 6 {
+		return now.AddDate(0, 0, -1)
+	}
+	return now
+}
+
+// Mostly blue days, with white and red days in winter
+func tempoColor(day time.Time) int {
+	if day.Month() > time.March && day.Month() < time.November {
+		return tempoBlue
+	}
+	switch {
+	case day.YearDay()%10 == 0 && day.Weekday() != time.Sunday:
+		return tempoRed
+	case day.YearDay()%4 == 0:
+		return tempoWhite
+	default:
+		return tempoBlue
+	}
+}
+
+// A few mobile peak days in winter
+func isEJPDay(day time.Time) bool {
+	return (day.Month() > time.October || day.Month() < time.April) && day.YearDay()%7 == 0
+}
+
+// Write historical information groups
+func (simulator *LinkySimulator) writeHistoricalGroups(frame *bytes.Buffer, now time.Time, power float64, index int) {
+	group := func(label, data string) {
+		writeGroup(frame, Historical, label, "", data)
+	}
+	energy := func(i int) string {
+		return fmt.Sprintf("%09d", int64(simulator.indexes[i]))
+	}
+
+	group("ADCO", simulator.Address)
+	switch simulator.Contract {
+	case ContractBase:
+		group("OPTARIF", "BASE")
+		group("ISOUSC", "30")
+		group("BASE", energy(0))
+		group("PTEC", "TH..")
+	case ContractHCHP:
+		group("OPTARIF", "HC..")
+		group("ISOUSC", "30")
+		group("HCHC", energy(0))
+		group("HCHP", energy(1))
+		group("PTEC", []string{"HC..", "HP.."}[index])
+	case ContractEJP:
+		group("OPTARIF", "EJP.")
+		group("ISOUSC", "30")
+		group("EJPHN", energy(0))
+		group("EJPHPM", energy(1))
+		group("PTEC", []string{"HN..", "PM.."}[index])
+		if isEJPDay(now) && now.Hour() == 6 && now.Minute() >= 30 {
+			group("PEJP", "30")
+		}
+	case ContractBBR:
+		group("OPTARIF", "BBR(")
+		group("ISOUSC", "45")
+		for i, label := range []string{"BBRHCJB", "BBRHPJB", "BBRHCJW", "BBRHPJW", "BBRHCJR", "BBRHPJR"} {
+			group(label, energy(i))
+		}
+		group("PTEC", []string{"HCJB", "HPJB", "HCJW", "HPJW", "HCJR", "HPJR"}[index])
+		demain := "----"
+		if now.Hour() >= 12 {
+			demain = []string{"BLEU", "BLAN", "ROUG"}[tempoColor(now.AddDate(0, 0, 1))-1]
+		}
+		group("DEMAIN", demain)
+	}
+	group("IINST", fmt.Sprintf("%03d", int(math.Round(power/230))))
+	group("IMAX", "090")
+	group("PAPP", fmt.Sprintf("%05d", int(math.Round(power/10)*10)))
+	if simulator.Contract != ContractBase {
+		group("HHPHC", "A")
+	}
+	group("MOTDETAT", "000000")
+}
+
+// Write standard information groups
+func (simulator *LinkySimulator) writeStandardGroups(frame *bytes.Buffer, now time.Time, power float64, index int) {
+	group := func(label, data string) {
+		writeGroup(frame, Standard, label, "", data)
+	}
+	datedGroup := func(label string, date time.Time, data string) {
+		writeGroup(frame, Standard, label, NewHorodate(date).String(), data)
+	}
+
+	tariff := standardTariffLabels[simulator.Contract][index]
+
+	var total float64
+	for _, energy := range simulator.indexes {
+		total += energy
+	}
+	voltage := 230 + simulator.random.IntN(7) - 3
+	halfHour := now.Truncate(30 * time.Minute)
+
+	group("ADSC", simulator.Address)
+	group("VTIC", "02")
+	datedGroup("DATE", now, "")
+	group("NGTF", fmt.Sprintf("%-16s", standardCalendars[simulator.Contract]))
+	group("LTARF", fmt.Sprintf("%-16s", tariff))
+	group("EAST", fmt.Sprintf("%09d", int64(total)))
+	for i := 0; i < 10; i++ {
+		energy := 0.0
+		if i < len(simulator.indexes) {
+			energy = simulator.indexes[i]
+		}
+		group(fmt.Sprintf("EASF%02d", i+1), fmt.Sprintf("%09d", int64(energy)))
+	}
+	group("EASD01", fmt.Sprintf("%09d", int64(total)))
+	for i := 2; i <= 4; i++ {
+		group(fmt.Sprintf("EASD%02d", i), "000000000")
+	}
+	group("IRMS1", fmt.Sprintf("%03d", int(math.Round(power/float64(voltage)))))
+	group("URMS1", fmt.Sprintf("%03d", voltage))
+	group("PREF", "09")
+	group("PCOUP", "09")
+	group("SINSTS", fmt.Sprintf("%05d", int(math.Round(power))))
+	datedGroup("SMAXSN", simulator.maxTime, fmt.Sprintf("%05d", int(math.Round(simulator.maxPower))))
+	datedGroup("SMAXSN-1", simulator.maxTime.AddDate(0, 0, -1), fmt.Sprintf("%05d", int(math.Round(simulator.maxPower*0.9))))
+	datedGroup("CCASN", halfHour, fmt.Sprintf("%05d", int(math.Round(simulator.Profile.Power(halfHour)))))
+	datedGroup("CCASN-1", halfHour.Add(-30*time.Minute), fmt.Sprintf("%05d", int(math.Round(simulator.Profile.Power(halfHour.Add(-30*time.Minute))))))
+	datedGroup("UMOY1", now.Truncate(10*time.Minute), fmt.Sprintf("%03d", voltage))
+	group("STGE", fmt.Sprintf("%08X", simulator.status(now, index)))
+	group("MSG1", "PAS DE          MESSAGE         ")
+	group("PRM", simulator.Prm)
+	group("RELAIS", "000")
+	group("NTARF", fmt.Sprintf("%02d", index+1))
+	group("NJOURF", "00")
+	group("NJOURF+1", "00")
+	group("PJOURF+1", simulator.nextDayProfile(now))
+}
+
+// Status register with communication bits, current index and tempo colors
+func (simulator *LinkySimulator) status(now time.Time, index int) uint32 {
+	status := uint32(0x00DA0000) | uint32(index)<<10
+	if simulator.Contract == ContractBBR {
+		status |= uint32(tempoColor(tempoDay(now)))<<24 | uint32(tempoColor(tempoDay(now).AddDate(0, 0, 1)))<<26
+	}
+	return status
+}
+
+// Next day profile made of index switches
+func (simulator *LinkySimulator) nextDayProfile(now time.Time) string {
+	var blocks []string
+	switch simulator.Contract {
+	case ContractBase:
+		blocks = []string{"00008001"}
+	case ContractHCHP:
+		blocks = []string{"00008001", "06008002", "22008001"}
+	case ContractEJP:
+		blocks = []string{"00008001"}
+		if isEJPDay(now.AddDate(0, 0, 1)) {
+			blocks = append(blocks, "07008002")
+		}
+	case ContractBBR:
+		today := (tempoColor(tempoDay(now)) - 1) * 2
+		tomorrow := (tempoColor(now.AddDate(0, 0, 1)) - 1) * 2
+		blocks = []string{
+			fmt.Sprintf("0000%04X", 0x8001+today),
+			fmt.Sprintf("0600%04X", 0x8002+tomorrow),
+			fmt.Sprintf("2200%04X", 0x8001+tomorrow),
+		}
+	}
+	for len(blocks) < 11 {
+		blocks = append(blocks, "NONUTILE")
+	}
+	return strings.Join(blocks, " ")
+}
+
+// Write one information group with its checksum
+func writeGroup(frame *bytes.Buffer, mode LinkyMode, label, horodate, data string) {
+	separator := string(rune(SP))
+	if mode == Standard {
+		separator = string(rune(HT))
+	}
+
+	fields := label + separator
+	if horodate != "" {
+		fields += horodate + separator
+	}
+	fields += data
+
+	frame.WriteByte(LF)
+	frame.WriteString(fields)
+	frame.WriteString(separator)
+	if mode == Standard {
+		frame.WriteByte(checksum(fields + separator))
+	} else {
+		frame.WriteByte(checksum(fields))
+	}
+	frame.WriteByte(CR)
+}
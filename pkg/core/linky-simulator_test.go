@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSimulatorFramesTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		mode     LinkyMode
+		contract string
+		name     string
+	}{
+		{Historical, ContractBase, "historical base"},
+		{Historical, ContractHCHP, "historical hchp"},
+		{Historical, ContractEJP, "historical ejp"},
+		{Historical, ContractBBR, "historical bbr"},
+		{Standard, ContractBase, "standard base"},
+		{Standard, ContractBBR, "standard bbr"},
+	}
+	start := time.Date(2024, time.January, 20, 18, 0, 0, 0, paris)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulator, err := NewLinkySimulator(tt.mode, tt.contract, DefaultLoadProfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			connector := LinkyConnector{Mode: tt.mode}
+			stream := append(simulator.Frame(start), simulator.Frame(start.Add(time.Hour))...)
+			reader := bufio.NewReader(bytes.NewReader(stream))
+
+			// When
+			first, _ := connector.readSerial(reader)
+			second, _ := connector.readSerial(reader)
+			before, after := connector.decode(first), connector.decode(second)
+
+			// Then
+			if len(connector.Stats().FrameErrors()) != 0 {
+				t.Errorf("got frame errors %v", connector.Stats().FrameErrors())
+			}
+			if len(connector.Stats().UnknownLabels()) != 0 {
+				t.Errorf("got unknown labels %v", connector.Stats().UnknownLabels())
+			}
+			if tt.mode == Standard && (after.Standard.East <= before.Standard.East || after.Standard.Sinsts == 0) {
+				t.Errorf("got energy %d then %d, want increasing", before.Standard.East, after.Standard.East)
+			}
+			if tt.mode == Historical && after.Historical.Papp == 0 {
+				t.Error("got no apparent power")
+			}
+		})
+	}
+}
+
+func TestLoadProfilePowerTableDriven(t *testing.T) {
+	// Given
+	profile, err := ParseLoadProfile("06:00=1000,18:00=3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		clock string
+		want  float64
+	}{
+		{"06:00", 1000},
+		{"12:00", 2000},
+		{"18:00", 3000},
+		{"00:00", 2000},
+		{"03:00", 1500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clock, func(t *testing.T) {
+			at, _ := time.Parse("15:04", tt.clock)
+
+			// When
+			got := profile.Power(at)
+
+			// Then
+			if got != tt.want {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
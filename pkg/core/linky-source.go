@@ -0,0 +1,119 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// TicSource opens the byte stream carrying TIC frames
+type TicSource interface {
+	Open() (io.ReadCloser, error)
+	String() string
+}
+
+// NewSource builds the source matching the device, a local serial device or an URL like tcp://host:port
+// or file:///path/capture.tic
+func NewSource(device string, mode *serial.Mode) (TicSource, error) {
+	if IsLocalDevice(device) {
+		return &SerialSource{Device: device, Mode: *mode}, nil
+	}
+
+	deviceUrl, err := url.Parse(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device URL %s: %w", device, err)
+	}
+
+	switch deviceUrl.Scheme {
+	case "tcp":
+		if deviceUrl.Port() == "" {
+			return nil, fmt.Errorf("missing port in device URL %s", device)
+		}
+		return &TCPSource{Address: deviceUrl.Host}, nil
+	case "file":
+		return newFileSource(deviceUrl, mode)
+	default:
+		return nil, fmt.Errorf("unsupported device scheme %s", deviceUrl.Scheme)
+	}
+}
+
+// IsLocalDevice checks if the device is a local file path and not an URL
+func IsLocalDevice(device string) bool {
+	return !strings.Contains(device, "://")
+}
+
+// SerialSource reads TIC from a local serial device
+type SerialSource struct {
+	Device string
+	Mode   serial.Mode
+}
+
+// Open serial port, reads fail if no data is received during FrameTimeout
+func (source *SerialSource) Open() (io.ReadCloser, error) {
+	port, err := serial.Open(source.Device, &source.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = port.SetReadTimeout(FrameTimeout)
+	if err != nil {
+		_ = port.Close()
+		return nil, err
+	}
+
+	return &serialStream{port}, nil
+}
+
+func (source *SerialSource) String() string {
+	return source.Device
+}
+
+// Serial port returning an error instead of an empty read on timeout
+type serialStream struct {
+	serial.Port
+}
+
+func (stream *serialStream) Read(p []byte) (int, error) {
+	n, err := stream.Port.Read(p)
+	if n == 0 && err == nil {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return n, err
+}
+
+// TCPSource reads TIC from a raw TCP bridge like ser2net or an ESP8266
+type TCPSource struct {
+	Address string
+}
+
+// Open TCP connection, reads fail if no data is received during FrameTimeout
+func (source *TCPSource) Open() (io.ReadCloser, error) {
+	conn, err := net.DialTimeout("tcp", source.Address, FrameTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpStream{conn}, nil
+}
+
+func (source *TCPSource) String() string {
+	return "tcp://" + source.Address
+}
+
+// TCP connection with a read deadline renewed before each read
+type tcpStream struct {
+	net.Conn
+}
+
+func (stream *tcpStream) Read(p []byte) (int, error) {
+	err := stream.SetReadDeadline(time.Now().Add(FrameTimeout))
+	if err != nil {
+		return 0, err
+	}
+	return stream.Conn.Read(p)
+}
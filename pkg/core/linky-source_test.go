@@ -0,0 +1,171 @@
+package core
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+const historicalFrame = "\x02\nADCO 021728123456 @\r\nISOUSC 30 9\r\nPAPP 00800 )\r\x03"
+
+// Serve historical frames to each connection then close it
+func serveFrames(t *testing.T, count int) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(strings.Repeat(historicalFrame, count)))
+			_ = conn.Close()
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestNewSourceTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		device string
+		want   string
+		err    bool
+	}{
+		{"/dev/ttyAMA0", "/dev/ttyAMA0", false},
+		{"tcp://192.168.1.10:3333", "tcp://192.168.1.10:3333", false},
+		{"tcp://192.168.1.10", "", true},
+		{"udp://192.168.1.10:3333", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.device, func(t *testing.T) {
+			// When
+			source, err := NewSource(tt.device, &serial.Mode{BaudRate: Historical.BaudRate})
+
+			// Then
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %t", err, tt.err)
+			}
+			if err == nil && source.String() != tt.want {
+				t.Errorf("got %s, want %s", source.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTCPSourceReconnect(t *testing.T) {
+	// Given
+	connector := &LinkyConnector{Mode: Historical, Device: serveFrames(t, 2)}
+
+	// When
+	connector.Start()
+	defer connector.Stop()
+
+	// Then
+	deadline := time.Now().Add(5 * time.Second)
+	for connector.Stats().Frames() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d frames, want at least 3 after reconnection", connector.Stats().Frames())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	values, err := connector.GetLastHistoricalTicValue()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if values.Papp != 800 {
+		t.Errorf("got %d, want 800", values.Papp)
+	}
+}
+
+func TestDetectOverTCP(t *testing.T) {
+	// Given
+	connector := &LinkyConnector{Device: serveFrames(t, 3)}
+
+	// When
+	err := connector.Detect()
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if connector.Mode != Historical {
+		t.Errorf("got %v, want historical mode", connector.Mode)
+	}
+}
+
+func TestFileSourceTableDriven(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "capture.tic")
+	if err := os.WriteFile(path, []byte(historicalFrame), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		query       string
+		read        int
+		minDuration time.Duration
+	}{
+		{"?pace=fast", 3 * len(historicalFrame), 0},
+		{"?pace=realtime", len(historicalFrame), 300 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			source, err := NewSource("file://"+path+tt.query, &serial.Mode{BaudRate: Historical.BaudRate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream, err := source.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = stream.Close() }()
+			start := time.Now()
+
+			// When
+			data, err := io.ReadAll(io.LimitReader(stream, int64(tt.read)))
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if string(data[:len(historicalFrame)]) != historicalFrame || len(data) != tt.read {
+				t.Errorf("got %q", data)
+			}
+			if time.Since(start) < tt.minDuration {
+				t.Errorf("replay took %s, want at least %s", time.Since(start), tt.minDuration)
+			}
+		})
+	}
+}
+
+func TestFileSourceWithoutLoop(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "capture.tic")
+	if err := os.WriteFile(path, []byte(historicalFrame), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	connector := &LinkyConnector{Mode: Historical, Device: "file://" + path + "?pace=fast&loop=false"}
+
+	// When
+	connector.Start()
+	time.Sleep(100 * time.Millisecond)
+	connector.Stop()
+
+	// Then
+	if got := connector.Stats().Frames(); got != 1 {
+		t.Errorf("got %d frames, want 1", got)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -104,7 +105,7 @@ func TestParseDateTableDrivenRelais(t *testing.T) {
 		testname := tt.value
 		t.Run(testname, func(t *testing.T) {
 			// When
-			tic.parseDate(tt.value)
+			tic.ParseParam("DATE", []string{tt.value, "", "D"})
 
 			// Then
 			if tic.Date.Unix() != tt.want {
@@ -191,3 +192,94 @@ func TestParseParam(t *testing.T) {
 		t.Error("Relais 1 not good")
 	}
 }
+
+func TestParseParamTableDrivenStatus(t *testing.T) {
+	// Given
+	var tests = []struct {
+		value                   string
+		today, tomorrow, cutOff uint8
+	}{
+		{"00DA0001", 0, 0, 0},
+		{"05DA0401", 1, 1, 0},
+		{"0EDA0002", 2, 3, 1},
+		{"0B00000E", 3, 2, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			tic := StandardTicValue{}
+
+			// When
+			tic.ParseParam("STGE", []string{tt.value, "K"})
+
+			// Then
+			if tic.TempoContractColorStatus != tt.today {
+				t.Errorf("got today %d, want %d", tic.TempoContractColorStatus, tt.today)
+			}
+			if tic.TempoContractNextDayColorStatus != tt.tomorrow {
+				t.Errorf("got tomorrow %d, want %d", tic.TempoContractNextDayColorStatus, tt.tomorrow)
+			}
+			if tic.CutOffDeviceStatus != tt.cutOff {
+				t.Errorf("got cut-off %d, want %d", tic.CutOffDeviceStatus, tt.cutOff)
+			}
+		})
+	}
+}
+
+func TestParseParamTableDrivenHorodate(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name   string
+		values []string
+		date   func(tic *StandardTicValue) Horodate
+		want   int64
+	}{
+		{"SMAXSN", []string{"H221113002750", "01750", "2"}, func(tic *StandardTicValue) Horodate { return tic.SmaxsnDate }, 1668295670},
+		{"CCASN-1", []string{"E220714153000", "01430", "P"}, func(tic *StandardTicValue) Horodate { return tic.CcasnlyDate }, 1657805400},
+		{"UMOY1", []string{"H221113002750", "236", ","}, func(tic *StandardTicValue) Horodate { return tic.Umoy1Date }, 1668295670},
+		{"DPM1", []string{"E220714153000", "00", "?"}, func(tic *StandardTicValue) Horodate { return tic.Dpm1Date }, 1657805400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tic := StandardTicValue{}
+
+			// When
+			tic.ParseParam(tt.name, tt.values)
+
+			// Then
+			if got := tt.date(&tic).Unix(); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseParamTableDrivenInvalidNumber(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name   string
+		values []string
+		err    error
+	}{
+		{"SINSTS", []string{"01700", "N"}, nil},
+		{"SINSTS", []string{"017#0", "N"}, ErrInvalidValue},
+		{"SMAXSN", []string{"H221113002750", "0I750", "2"}, ErrInvalidValue},
+		{"STGE", []string{"00DA000Z", "K"}, ErrInvalidValue},
+		{"EAST", []string{"99999999999", "K"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.values[len(tt.values)-2], func(t *testing.T) {
+			tic := StandardTicValue{}
+
+			// When
+			err := tic.ParseParam(tt.name, tt.values)
+
+			// Then
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Frame error reasons
+const (
+	ReasonOpen        = "open"        // Serial port can't be opened
+	ReasonDetect      = "detect"      // TIC mode can't be auto detected
+	ReasonRead        = "read"        // Serial port read failed
+	ReasonInterrupted = "interrupted" // Frame interrupted by the meter
+	ReasonChecksum    = "checksum"    // Group with invalid checksum
+	ReasonMalformed   = "malformed"   // Group without expected separators
+	ReasonParse       = "parse"       // Group value can't be parsed
+)
+
+// MaxCountedLabels bounds the distinct labels counted by checksum errors and unknown labels, others are
+// counted as OtherLabel so that line noise can't create unlimited metric series
+const MaxCountedLabels = 32
+
+// OtherLabel counts labels over MaxCountedLabels
+const OtherLabel = "other"
+
+// Upper bounds in seconds of the frame read duration histogram
+var FrameReadDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8}
+
+// Histogram is a snapshot of cumulative observations
+type Histogram struct {
+	Count   uint64
+	Sum     float64
+	Buckets map[float64]uint64 // Cumulative count per upper bound
+}
+
+// LinkyStats counts connector events, safe for concurrent use
+type LinkyStats struct {
+	mutex          sync.Mutex
+	frames         uint64
+	frameErrors    map[string]uint64
+	checksumErrors map[string]uint64
+	unknownLabels  map[string]uint64
+	readDurations  Histogram
+}
+
+// Count one successfully read frame
+func (stats *LinkyStats) addFrame(duration time.Duration) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	stats.frames++
+
+	seconds := duration.Seconds()
+	if stats.readDurations.Buckets == nil {
+		stats.readDurations.Buckets = make(map[float64]uint64, len(FrameReadDurationBuckets))
+	}
+	stats.readDurations.Count++
+	stats.readDurations.Sum += seconds
+	for _, bound := range FrameReadDurationBuckets {
+		if seconds <= bound {
+			stats.readDurations.Buckets[bound]++
+		}
+	}
+}
+
+// Count one error by reason
+func (stats *LinkyStats) addFrameError(reason string) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	increment(&stats.frameErrors, reason)
+}
+
+// Count one group rejected because of its checksum
+func (stats *LinkyStats) addChecksumError(label string) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	increment(&stats.frameErrors, ReasonChecksum)
+	incrementLabel(&stats.checksumErrors, label)
+}
+
+// Count one group ignored because its label is unknown
+func (stats *LinkyStats) addUnknownLabel(label string) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	incrementLabel(&stats.unknownLabels, label)
+}
+
+// Frames returns the count of successfully read frames
+func (stats *LinkyStats) Frames() uint64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return stats.frames
+}
+
+// FrameErrors returns a copy of errors count per reason
+func (stats *LinkyStats) FrameErrors() map[string]uint64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return copyCounts(stats.frameErrors)
+}
+
+// ChecksumErrors returns a copy of rejected groups count per label
+func (stats *LinkyStats) ChecksumErrors() map[string]uint64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return copyCounts(stats.checksumErrors)
+}
+
+// UnknownLabels returns a copy of ignored groups count per label
+func (stats *LinkyStats) UnknownLabels() map[string]uint64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return copyCounts(stats.unknownLabels)
+}
+
+// ReadDurations returns a copy of the frame read duration histogram
+func (stats *LinkyStats) ReadDurations() Histogram {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	histogram := stats.readDurations
+	histogram.Buckets = make(map[float64]uint64, len(FrameReadDurationBuckets))
+	for _, bound := range FrameReadDurationBuckets {
+		histogram.Buckets[bound] = stats.readDurations.Buckets[bound]
+	}
+	return histogram
+}
+
+// Increment the counter of a label, or OtherLabel when too many labels are already counted
+func incrementLabel(counts *map[string]uint64, label string) {
+	if _, found := (*counts)[label]; !found && len(*counts) >= MaxCountedLabels {
+		label = OtherLabel
+	}
+	increment(counts, label)
+}
+
+// Increment a lazily created counter map
+func increment(counts *map[string]uint64, key string) {
+	if *counts == nil {
+		*counts = make(map[string]uint64)
+	}
+	(*counts)[key]++
+}
+
+// Copy a counter map
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	copied := make(map[string]uint64, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUnknownLabelsAreCapped(t *testing.T) {
+	// Given
+	stats := LinkyStats{}
+
+	// When
+	for i := range MaxCountedLabels + 10 {
+		stats.addUnknownLabel(fmt.Sprintf("NOISE%d", i))
+	}
+	stats.addUnknownLabel("NOISE0")
+
+	// Then
+	labels := stats.UnknownLabels()
+	if len(labels) != MaxCountedLabels+1 {
+		t.Errorf("got %d labels, want %d", len(labels), MaxCountedLabels+1)
+	}
+	if labels["NOISE0"] != 2 || labels[OtherLabel] != 10 {
+		t.Errorf("got NOISE0 %d and other %d, want 2 and 10", labels["NOISE0"], labels[OtherLabel])
+	}
+}
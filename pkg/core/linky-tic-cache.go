@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnknownLabel is returned when parsing a group label not handled by a TIC value
+var ErrUnknownLabel = errors.New("unknown label")
+
+// ErrInvalidValue is returned when parsing a group value which is not a number
+var ErrInvalidValue = errors.New("invalid value")
+
+// TicFrame is one decoded TIC frame with its reception time
+type TicFrame struct {
+	Standard   *StandardTicValue
+	Historical *HistoricalTicValue
+	Groups     []TicGroup // Groups with a valid checksum, in reception order
+	ReceivedAt time.Time
+}
+
+// TicGroup is a raw information group of a frame
+type TicGroup struct {
+	Label    string
+	Horodate string // Only sent by some standard mode groups
+	Value    string
+	Checksum string
+}
+
+// TicCache keeps the last decoded TIC frame and publishes new ones to subscribers, safe for concurrent use
+type TicCache struct {
+	mutex       sync.RWMutex
+	frame       *TicFrame
+	subscribers map[<-chan *TicFrame]chan *TicFrame
+}
+
+// Store replaces the cached frame and sends it to subscribers, skipping those not keeping up
+func (cache *TicCache) Store(frame *TicFrame) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.frame = frame
+
+	for _, subscriber := range cache.subscribers {
+		select {
+		case subscriber <- frame:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving each stored frame, buffering up to size frames
+func (cache *TicCache) Subscribe(size int) <-chan *TicFrame {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.subscribers == nil {
+		cache.subscribers = make(map[<-chan *TicFrame]chan *TicFrame)
+	}
+	subscriber := make(chan *TicFrame, size)
+	cache.subscribers[subscriber] = subscriber
+	return subscriber
+}
+
+// Unsubscribe stops sending frames to a subscribed channel and closes it
+func (cache *TicCache) Unsubscribe(subscription <-chan *TicFrame) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if subscriber, found := cache.subscribers[subscription]; found {
+		delete(cache.subscribers, subscription)
+		close(subscriber)
+	}
+}
+
+// Load returns the last cached frame, nil if none has been received yet
+func (cache *TicCache) Load() *TicFrame {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	return cache.frame
+}
@@ -0,0 +1,345 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// Default batching values
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 10 * time.Second
+	DefaultSpoolMaxSize  = 100 << 20
+)
+
+const (
+	// WriteTimeout bounds each write request
+	WriteTimeout = 10 * time.Second
+	// MaxRetries is the number of retries of a failed write before spooling it
+	MaxRetries = 3
+	// Initial delay before retrying a failed write, doubled on each retry
+	RetryDelay = 1 * time.Second
+	// MaxPendingBatches is the number of batches waiting to be written, next ones are spooled directly
+	MaxPendingBatches = 10
+)
+
+// Suffix of spooled batch files
+const spoolExtension = ".lp"
+
+// errPermanent marks write errors not worth retrying, like a bad request
+var errPermanent = errors.New("permanent write error")
+
+// LinkyWriter writes every decoded frame of the meters to the InfluxDB v2 write API as line protocol.
+// Points are written by batches in background, and batches failing after retries or while too many are
+// waiting are spooled to disk then written again once the server is reachable.
+type LinkyWriter struct {
+	URL           string // InfluxDB base URL, like http://localhost:8086
+	Org           string
+	Bucket        string
+	Token         string
+	BatchSize     int           // Maximum number of points in a batch
+	FlushInterval time.Duration // Maximum time a point waits in a batch
+	SpoolDir      string        // Directory of batches not written yet, dropped if empty
+	SpoolMaxSize  int64         // Maximum size in bytes of the spool directory, the oldest batches are dropped first
+
+	client     http.Client
+	spoolMutex sync.Mutex
+}
+
+// Run writes the meters frames until the context is done, then writes or spools the last batch
+func (writer *LinkyWriter) Run(ctx context.Context, meters map[string]*core.LinkyConnector) {
+	writer.client.Timeout = WriteTimeout
+	if writer.SpoolDir != "" {
+		err := os.MkdirAll(writer.SpoolDir, 0o700)
+		if err != nil {
+			slog.Error("Unable to create InfluxDB spool directory", "directory", writer.SpoolDir, "error", err)
+		}
+	}
+
+	lines := make(chan []byte, writer.BatchSize)
+	var wg sync.WaitGroup
+	for meter, connector := range meters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			frames := connector.Subscribe(writer.BatchSize)
+			defer connector.Unsubscribe(frames)
+			writer.encode(ctx, meter, frames, lines)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	// Batches are written in background so that a slow server never delays frames reading
+	batches := make(chan []byte, MaxPendingBatches)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		writer.send(ctx, batches)
+	}()
+	writer.batch(lines, batches)
+	close(batches)
+	<-sent
+}
+
+// Encode frames as line protocol until the context is done or frames is closed
+func (writer *LinkyWriter) encode(ctx context.Context, meter string, frames <-chan *core.TicFrame, lines chan<- []byte) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			timeSerie := prom.ConvertFrameToTimeSerie(frame)
+			if timeSerie == nil {
+				continue
+			}
+			line := Line(meter, timeSerie, frame.ReceivedAt)
+			if line == nil {
+				continue
+			}
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Group lines by batches queued when full or after the flush interval, until lines is closed.
+// Batches are spooled when the queue is full.
+func (writer *LinkyWriter) batch(lines <-chan []byte, batches chan<- []byte) {
+	ticker := time.NewTicker(writer.FlushInterval)
+	defer ticker.Stop()
+
+	var batch bytes.Buffer
+	points := 0
+	flush := func() {
+		if points == 0 {
+			return
+		}
+		select {
+		case batches <- bytes.Clone(batch.Bytes()):
+		default:
+			slog.Warn("InfluxDB writes too slow, spooling batch", "points", points)
+			writer.spool(bytes.Clone(batch.Bytes()))
+		}
+		batch.Reset()
+		points = 0
+	}
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flush()
+				return
+			}
+			batch.Write(line)
+			points++
+			if points >= writer.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Write queued batches until batches is closed. Once the context is done, the remaining batches are written
+// during WriteTimeout at most, or spooled.
+func (writer *LinkyWriter) send(ctx context.Context, batches <-chan []byte) {
+	var shutdownCtx context.Context
+	for batch := range batches {
+		if ctx.Err() != nil && shutdownCtx == nil {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(context.Background(), WriteTimeout)
+			defer cancel()
+		}
+		if shutdownCtx != nil {
+			writer.flush(shutdownCtx, batch)
+		} else {
+			writer.flush(ctx, batch)
+		}
+	}
+}
+
+// Write a batch, spooling it on failure, and write the spooled batches once the server answers
+func (writer *LinkyWriter) flush(ctx context.Context, batch []byte) {
+	err := writer.writeWithRetry(ctx, batch)
+	if err != nil {
+		slog.Error("Unable to write to InfluxDB", "url", writer.URL, "error", err)
+		if !errors.Is(err, errPermanent) {
+			writer.spool(batch)
+		}
+		return
+	}
+	writer.replay(ctx)
+}
+
+// Write a batch, retrying with backoff while the server is unreachable or overloaded
+func (writer *LinkyWriter) writeWithRetry(ctx context.Context, batch []byte) error {
+	delay := RetryDelay
+	for retry := 0; ; retry++ {
+		err := writer.write(ctx, batch)
+		if err == nil || errors.Is(err, errPermanent) || retry == MaxRetries {
+			return err
+		}
+		slog.Debug("Retrying InfluxDB write", "error", err, "retry", delay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Send a batch to the write API
+func (writer *LinkyWriter) write(ctx context.Context, batch []byte) error {
+	query := url.Values{"org": {writer.Org}, "bucket": {writer.Bucket}, "precision": {"ns"}}
+	endpoint := strings.TrimSuffix(writer.URL, "/") + "/api/v2/write?" + query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(batch))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPermanent, err)
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if writer.Token != "" {
+		request.Header.Set("Authorization", "Token "+writer.Token)
+	}
+
+	response, err := writer.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("unexpected status %s: %s", response.Status, strings.TrimSpace(string(body)))
+	if response.StatusCode >= http.StatusBadRequest && response.StatusCode < http.StatusInternalServerError &&
+		response.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", errPermanent, err)
+	}
+	return err
+}
+
+// Save a batch to the spool directory, then drop the oldest batches over SpoolMaxSize
+func (writer *LinkyWriter) spool(batch []byte) {
+	if writer.SpoolDir == "" {
+		slog.Warn("Dropping InfluxDB batch, no spool directory", "bytes", len(batch))
+		return
+	}
+	writer.spoolMutex.Lock()
+	defer writer.spoolMutex.Unlock()
+
+	path := filepath.Join(writer.SpoolDir, fmt.Sprintf("%020d%s", time.Now().UnixNano(), spoolExtension))
+	err := os.WriteFile(path, batch, 0o600)
+	if err != nil {
+		slog.Error("Unable to spool InfluxDB batch", "file", path, "error", err)
+		return
+	}
+	slog.Info("InfluxDB batch spooled", "file", path)
+
+	if writer.SpoolMaxSize > 0 {
+		writer.trimSpool()
+	}
+}
+
+// Remove the oldest spooled batches until the spool fits in SpoolMaxSize
+func (writer *LinkyWriter) trimSpool() {
+	files := writer.spooledFiles()
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		sizes[i] = info.Size()
+		total += sizes[i]
+	}
+
+	for i := 0; total > writer.SpoolMaxSize && i < len(files)-1; i++ {
+		err := os.Remove(files[i])
+		if err != nil {
+			slog.Error("Unable to remove spooled InfluxDB batch", "file", files[i], "error", err)
+			continue
+		}
+		slog.Warn("InfluxDB spool full, dropping oldest batch", "file", files[i], "max_size", writer.SpoolMaxSize)
+		total -= sizes[i]
+	}
+}
+
+// Spooled batches files, oldest first
+func (writer *LinkyWriter) spooledFiles() []string {
+	files, err := filepath.Glob(filepath.Join(writer.SpoolDir, "*"+spoolExtension))
+	if err != nil {
+		slog.Error("Unable to list InfluxDB spool", "directory", writer.SpoolDir, "error", err)
+		return nil
+	}
+	slices.Sort(files)
+	return files
+}
+
+// Write spooled batches from the oldest, until the first failure
+func (writer *LinkyWriter) replay(ctx context.Context) {
+	if writer.SpoolDir == "" {
+		return
+	}
+	writer.spoolMutex.Lock()
+	files := writer.spooledFiles()
+	writer.spoolMutex.Unlock()
+
+	for _, file := range files {
+		writer.spoolMutex.Lock()
+		batch, err := os.ReadFile(file)
+		writer.spoolMutex.Unlock()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Dropped meanwhile because the spool is full
+			continue
+		}
+		if err != nil {
+			slog.Error("Unable to read spooled InfluxDB batch", "file", file, "error", err)
+			return
+		}
+		err = writer.write(ctx, batch)
+		if err != nil && !errors.Is(err, errPermanent) {
+			return
+		}
+		if err != nil {
+			slog.Error("Dropping spooled InfluxDB batch", "file", file, "error", err)
+		}
+		writer.spoolMutex.Lock()
+		err = os.Remove(file)
+		writer.spoolMutex.Unlock()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Error("Unable to remove spooled InfluxDB batch", "file", file, "error", err)
+			return
+		}
+		slog.Debug("Spooled InfluxDB batch written", "file", file)
+	}
+}
@@ -0,0 +1,175 @@
+package influx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// Decode a frame from its groups
+func decodeFrame(frame *core.TicFrame, groups ...core.TicGroup) *core.TicFrame {
+	frame.Groups = groups
+	for _, group := range groups {
+		if frame.Standard != nil {
+			_ = frame.Standard.ParseParam(group.Label, []string{group.Value, group.Checksum})
+		} else {
+			_ = frame.Historical.ParseParam(group.Label, []string{group.Value, group.Checksum})
+		}
+	}
+	return frame
+}
+
+func TestLineTableDriven(t *testing.T) {
+	// Given
+	date := time.Unix(1700000000, 0)
+	historical := func(groups ...core.TicGroup) *core.TicFrame {
+		return decodeFrame(&core.TicFrame{Historical: &core.HistoricalTicValue{}}, groups...)
+	}
+	standard := func(groups ...core.TicGroup) *core.TicFrame {
+		return decodeFrame(&core.TicFrame{Standard: &core.StandardTicValue{}}, groups...)
+	}
+	var tests = []struct {
+		name     string
+		frame    *core.TicFrame
+		expected string
+	}{
+		{
+			"historical",
+			historical(
+				core.TicGroup{Label: "ADCO", Value: "XXXX"}, core.TicGroup{Label: "OPTARIF", Value: "BASE"},
+				core.TicGroup{Label: "PTEC", Value: "TH.."}, core.TicGroup{Label: "BASE", Value: "000001234"},
+				core.TicGroup{Label: "PAPP", Value: "00750"},
+			),
+			"linky,contract=BASE,linky_id=XXXX,meter=house,pricing=TH.. energy_used_index1=1234,power_used=750 1700000000000000000\n",
+		},
+		{
+			"zero values",
+			historical(
+				core.TicGroup{Label: "ADCO", Value: "XXXX"}, core.TicGroup{Label: "IINST", Value: "000"},
+				core.TicGroup{Label: "PAPP", Value: "00000"},
+			),
+			"linky,linky_id=XXXX,meter=house intensity_p1=0,power_used=0 1700000000000000000\n",
+		},
+		{
+			"escaped tags",
+			standard(
+				core.TicGroup{Label: "ADSC", Value: "XXXX"}, core.TicGroup{Label: "NGTF", Value: "H PLEINE/CREUSE"},
+				core.TicGroup{Label: "LTARF", Value: "HC  BLEU"}, core.TicGroup{Label: "SINSTI", Value: "00000"},
+			),
+			`linky,contract=H\ PLEINE/CREUSE,linky_id=XXXX,meter=house,pricing=HC\ \ BLEU power_produced=0 1700000000000000000` + "\n",
+		},
+		{"no value", historical(core.TicGroup{Label: "ADCO", Value: "XXXX"}), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			line := Line("house", prom.ConvertFrameToTimeSerie(tt.frame), date)
+
+			// Then
+			if string(line) != tt.expected {
+				t.Errorf("got %q, want %q", line, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFlushReplaysSpool(t *testing.T) {
+	// Given
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/write" || r.URL.Query().Get("bucket") != "energy" || r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	writer := LinkyWriter{URL: server.URL, Bucket: "energy", Token: "secret", SpoolDir: t.TempDir()}
+	writer.spool([]byte("linky power_used=1 1\n"))
+
+	// When
+	writer.flush(context.Background(), []byte("linky power_used=2 2\n"))
+
+	// Then
+	if strings.Join(bodies, "") != "linky power_used=2 2\nlinky power_used=1 1\n" {
+		t.Errorf("got %q", bodies)
+	}
+	files, _ := filepath.Glob(filepath.Join(writer.SpoolDir, "*"))
+	if len(files) != 0 {
+		t.Errorf("got spooled files %v", files)
+	}
+}
+
+func TestFlushDropsRejectedBatch(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unable to parse", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	writer := LinkyWriter{URL: server.URL, Bucket: "energy", SpoolDir: t.TempDir()}
+
+	// When
+	writer.flush(context.Background(), []byte("invalid\n"))
+
+	// Then
+	entries, err := os.ReadDir(writer.SpoolDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("got spooled files %v, error %v", entries, err)
+	}
+}
+
+func TestBatchSpoolsWhileWritesAreSlow(t *testing.T) {
+	// Given
+	writer := LinkyWriter{BatchSize: 1, FlushInterval: time.Hour, SpoolDir: t.TempDir()}
+	lines := make(chan []byte, 3)
+	for i := range 3 {
+		lines <- []byte(fmt.Sprintf("linky power_used=%d %d\n", i, i))
+	}
+	close(lines)
+	batches := make(chan []byte, 1)
+
+	// When
+	writer.batch(lines, batches)
+
+	// Then
+	if batch := <-batches; string(batch) != "linky power_used=0 0\n" {
+		t.Errorf("got queued batch %q", batch)
+	}
+	files, _ := filepath.Glob(filepath.Join(writer.SpoolDir, "*"))
+	if len(files) != 2 {
+		t.Errorf("got spooled files %v, want 2", files)
+	}
+}
+
+func TestSpoolDropsOldestBatches(t *testing.T) {
+	// Given
+	writer := LinkyWriter{SpoolDir: t.TempDir(), SpoolMaxSize: 50}
+
+	// When
+	for i := range 3 {
+		writer.spool([]byte(fmt.Sprintf("linky power_used=%d %d\n", i, i)))
+	}
+
+	// Then
+	files := writer.spooledFiles()
+	if len(files) != 2 {
+		t.Fatalf("got spooled files %v, want 2", files)
+	}
+	oldest, _ := os.ReadFile(files[0])
+	if string(oldest) != "linky power_used=1 1\n" {
+		t.Errorf("got oldest batch %q", oldest)
+	}
+}
@@ -0,0 +1,82 @@
+package influx
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// Measurement of the written points
+const Measurement = "linky"
+
+// Field of the time serie written as line protocol field
+type field struct {
+	name  string // Snake case name of the line protocol field
+	serie string // Name of the time serie field
+	index int
+}
+
+// Numeric fields of LinkyTimeSerie with their snake case names
+var fields = numericFields()
+
+func numericFields() []field {
+	var fields []field
+	serieType := reflect.TypeFor[prom.LinkyTimeSerie]()
+	for i := range serieType.NumField() {
+		if serieType.Field(i).Type.Kind() == reflect.Float64 {
+			fields = append(fields, field{name: core.SnakeCase(serieType.Field(i).Name), serie: serieType.Field(i).Name, index: i})
+		}
+	}
+	return fields
+}
+
+// Escape characters of tag keys, tag values and measurement
+var tagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// Line encodes a time serie as a line protocol point with the values sent by the meter, zero values included.
+// Returns nil if no value has been sent.
+func Line(meter string, timeSerie *prom.LinkyTimeSerie, date time.Time) []byte {
+	var line []byte
+	line = append(line, Measurement...)
+	tags := [][2]string{
+		{"contract", timeSerie.ContractTypeName},
+		{"linky_id", timeSerie.LinkyId},
+		{"meter", meter},
+		{"pricing", timeSerie.PriceLabel},
+	}
+	for _, tag := range tags {
+		value := strings.TrimSpace(tag[1])
+		if value == "" {
+			continue
+		}
+		line = append(line, ',')
+		line = append(line, tag[0]...)
+		line = append(line, '=')
+		line = append(line, tagEscaper.Replace(value)...)
+	}
+
+	separator := byte(' ')
+	values := reflect.ValueOf(timeSerie).Elem()
+	for _, field := range fields {
+		if !timeSerie.Sent(field.serie) {
+			continue
+		}
+		value := values.Field(field.index).Float()
+		line = append(line, separator)
+		line = append(line, field.name...)
+		line = append(line, '=')
+		line = strconv.AppendFloat(line, value, 'f', -1, 64)
+		separator = ','
+	}
+	if separator == ' ' {
+		return nil
+	}
+
+	line = append(line, ' ')
+	line = strconv.AppendInt(line, date.UnixNano(), 10)
+	return append(line, '\n')
+}
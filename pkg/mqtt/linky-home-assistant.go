@@ -0,0 +1,191 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// Home Assistant device and state classes used by the sensors
+const (
+	deviceClassEnergy        = "energy"
+	deviceClassApparentPower = "apparent_power"
+	deviceClassPower         = "power"
+	deviceClassCurrent       = "current"
+	deviceClassVoltage       = "voltage"
+
+	stateClassMeasurement     = "measurement"
+	stateClassTotalIncreasing = "total_increasing"
+)
+
+// Message is an MQTT message to publish
+type Message struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+}
+
+// Sensor is a Home Assistant entity read from a time serie, only announced once the meter sends it
+type Sensor struct {
+	Key         string // Key in the state payload and suffix of the entity unique id
+	Name        string
+	DeviceClass string
+	StateClass  string
+	Unit        string
+	Value       func(ts *prom.LinkyTimeSerie) any // Value of the sensor, nil if not sent by the meter
+}
+
+// Sensors published for each meter
+var Sensors = []Sensor{
+	{"energy_total", "Energy", deviceClassEnergy, stateClassTotalIncreasing, "Wh", totalEnergy},
+	{"energy_index_1", "Energy index 1", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex1")},
+	{"energy_index_2", "Energy index 2", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex2")},
+	{"energy_index_3", "Energy index 3", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex3")},
+	{"energy_index_4", "Energy index 4", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex4")},
+	{"energy_index_5", "Energy index 5", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex5")},
+	{"energy_index_6", "Energy index 6", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex6")},
+	{"energy_index_7", "Energy index 7", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex7")},
+	{"energy_index_8", "Energy index 8", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex8")},
+	{"energy_index_9", "Energy index 9", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex9")},
+	{"energy_index_10", "Energy index 10", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("EnergyUsedIndex10")},
+	{"energy_produced_total", "Energy produced", deviceClassEnergy, stateClassTotalIncreasing, "Wh", number("TotalEnergyProduced")},
+	{"power", "Power", deviceClassApparentPower, stateClassMeasurement, "VA", number("PowerUsed")},
+	{"power_produced", "Power produced", deviceClassApparentPower, stateClassMeasurement, "VA", number("PowerProduced")},
+	{"power_max", "Power max today", deviceClassApparentPower, stateClassMeasurement, "VA", number("PowerUsedMax")},
+	{"load_curve_point", "Load curve point", deviceClassPower, stateClassMeasurement, "W", number("UsedLoadCurvePoint")},
+	{"intensity_p1", "Current phase 1", deviceClassCurrent, stateClassMeasurement, "A", number("IntensityP1")},
+	{"intensity_p2", "Current phase 2", deviceClassCurrent, stateClassMeasurement, "A", number("IntensityP2")},
+	{"intensity_p3", "Current phase 3", deviceClassCurrent, stateClassMeasurement, "A", number("IntensityP3")},
+	{"voltage_p1", "Voltage phase 1", deviceClassVoltage, stateClassMeasurement, "V", number("VoltageP1")},
+	{"voltage_p2", "Voltage phase 2", deviceClassVoltage, stateClassMeasurement, "V", number("VoltageP2")},
+	{"voltage_p3", "Voltage phase 3", deviceClassVoltage, stateClassMeasurement, "V", number("VoltageP3")},
+	{"reference_power", "Subscribed power", deviceClassApparentPower, "", "kVA", number("ReferencePower")},
+	{"tariff", "Tariff", "", "", "", text(func(ts *prom.LinkyTimeSerie) string { return ts.PriceLabel })},
+	{"today_color", "Today color", "", "", "", text(func(ts *prom.LinkyTimeSerie) string { return ts.TodayColor })},
+	{"tomorrow_color", "Tomorrow color", "", "", "", text(func(ts *prom.LinkyTimeSerie) string { return ts.TomorrowColor })},
+}
+
+// Value of a numeric field of the time serie, zero values included when sent by the meter
+func number(field string) func(ts *prom.LinkyTimeSerie) any {
+	return func(ts *prom.LinkyTimeSerie) any {
+		if !ts.Sent(field) {
+			return nil
+		}
+		return reflect.ValueOf(ts).Elem().FieldByName(field).Float()
+	}
+}
+
+func text(value func(ts *prom.LinkyTimeSerie) string) func(ts *prom.LinkyTimeSerie) any {
+	return func(ts *prom.LinkyTimeSerie) any {
+		if v := value(ts); v != "" {
+			return v
+		}
+		return nil
+	}
+}
+
+// Total used energy, the sum of the indexes in historical mode which doesn't send it
+func totalEnergy(ts *prom.LinkyTimeSerie) any {
+	if ts.Sent("TotalEnergyUsed") {
+		return ts.TotalEnergyUsed
+	}
+	indexes := []float64{
+		ts.EnergyUsedIndex1, ts.EnergyUsedIndex2, ts.EnergyUsedIndex3, ts.EnergyUsedIndex4, ts.EnergyUsedIndex5,
+		ts.EnergyUsedIndex6, ts.EnergyUsedIndex7, ts.EnergyUsedIndex8, ts.EnergyUsedIndex9, ts.EnergyUsedIndex10,
+	}
+	total, sent := 0.0, false
+	for i, index := range indexes {
+		if ts.Sent(fmt.Sprintf("EnergyUsedIndex%d", i+1)) {
+			total += index
+			sent = true
+		}
+	}
+	if !sent {
+		return nil
+	}
+	return total
+}
+
+// Device groups the entities of a meter in Home Assistant
+type Device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+	SerialNumber string   `json:"serial_number"`
+}
+
+// DiscoveryConfig is the Home Assistant MQTT discovery payload of a sensor
+type DiscoveryConfig struct {
+	Name              string `json:"name"`
+	UniqueId          string `json:"unique_id"`
+	ObjectId          string `json:"object_id"`
+	StateTopic        string `json:"state_topic"`
+	ValueTemplate     string `json:"value_template"`
+	AvailabilityTopic string `json:"availability_topic"`
+	DeviceClass       string `json:"device_class,omitempty"`
+	StateClass        string `json:"state_class,omitempty"`
+	Unit              string `json:"unit_of_measurement,omitempty"`
+	Device            Device `json:"device"`
+}
+
+// Topics of the published messages
+type Topics struct {
+	Prefix          string // Prefix of state and availability topics
+	DiscoveryPrefix string // Home Assistant discovery prefix, discovery disabled if empty
+}
+
+// State returns the topic of the meter state
+func (topics Topics) State(ts *prom.LinkyTimeSerie) string {
+	return fmt.Sprintf("%s/%s/state", topics.Prefix, ts.LinkyId)
+}
+
+// Availability returns the topic of the exporter availability
+func (topics Topics) Availability() string {
+	return topics.Prefix + "/status"
+}
+
+// StateMessage builds the state of a meter with the values of every sensor sent
+func (topics Topics) StateMessage(meter string, ts *prom.LinkyTimeSerie) (Message, error) {
+	state := map[string]any{"meter": meter}
+	for _, sensor := range Sensors {
+		if value := sensor.Value(ts); value != nil {
+			state[sensor.Key] = value
+		}
+	}
+
+	payload, err := json.Marshal(state)
+	return Message{Topic: topics.State(ts), Payload: payload}, err
+}
+
+// DiscoveryMessage builds the retained discovery config of a sensor
+func (topics Topics) DiscoveryMessage(meter string, ts *prom.LinkyTimeSerie, sensor Sensor) (Message, error) {
+	id := "linky_" + ts.LinkyId
+	device := Device{
+		Identifiers:  []string{id},
+		Name:         "Linky " + meter,
+		Manufacturer: "Enedis",
+		Model:        "Linky " + ts.ContractTypeName,
+		SerialNumber: ts.LinkyId,
+	}
+	if ts.Prm != "" {
+		device.Identifiers = append(device.Identifiers, "linky_prm_"+ts.Prm)
+	}
+
+	payload, err := json.Marshal(DiscoveryConfig{
+		Name:              sensor.Name,
+		UniqueId:          id + "_" + sensor.Key,
+		ObjectId:          id + "_" + sensor.Key,
+		StateTopic:        topics.State(ts),
+		ValueTemplate:     fmt.Sprintf("{{ value_json.%s }}", sensor.Key),
+		AvailabilityTopic: topics.Availability(),
+		DeviceClass:       sensor.DeviceClass,
+		StateClass:        sensor.StateClass,
+		Unit:              sensor.Unit,
+		Device:            device,
+	})
+	topic := fmt.Sprintf("%s/sensor/%s/%s/config", topics.DiscoveryPrefix, id, sensor.Key)
+	return Message{Topic: topic, Payload: payload, Retained: true}, err
+}
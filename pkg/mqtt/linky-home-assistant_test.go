@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// Decode a historical frame from its groups
+func historicalFrame(groups ...core.TicGroup) *core.TicFrame {
+	frame := &core.TicFrame{Historical: &core.HistoricalTicValue{}, Groups: groups}
+	for _, group := range groups {
+		_ = frame.Historical.ParseParam(group.Label, []string{group.Value, group.Checksum})
+	}
+	return frame
+}
+
+func TestStateMessageHistoricalTotal(t *testing.T) {
+	// Given
+	topics := Topics{Prefix: DefaultTopicPrefix, DiscoveryPrefix: DefaultDiscoveryPrefix}
+	timeSerie := prom.ConvertFrameToTimeSerie(historicalFrame(
+		core.TicGroup{Label: "ADCO", Value: "XXXX"},
+		core.TicGroup{Label: "BBRHCJB", Value: "000001000"},
+		core.TicGroup{Label: "BBRHPJB", Value: "000000500"},
+		core.TicGroup{Label: "IINST", Value: "000"},
+		core.TicGroup{Label: "PAPP", Value: "00750"},
+	))
+
+	// When
+	message, err := topics.StateMessage("house", timeSerie)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var state map[string]any
+	if err := json.Unmarshal(message.Payload, &state); err != nil {
+		t.Fatal(err)
+	}
+	if message.Topic != "linky/XXXX/state" || message.Retained {
+		t.Errorf("got topic %s retained %t", message.Topic, message.Retained)
+	}
+	if state["meter"] != "house" || state["energy_total"] != 1500.0 || state["power"] != 750.0 {
+		t.Errorf("got %v", state)
+	}
+	if !strings.Contains(string(message.Payload), `"intensity_p1":0`) {
+		t.Errorf("got %s, want the current sent at zero", message.Payload)
+	}
+	for _, key := range []string{"voltage_p1", "intensity_p2", "energy_index_3"} {
+		if _, found := state[key]; found {
+			t.Errorf("got %s not sent by the meter in %v", key, state)
+		}
+	}
+}
+
+func TestDiscoveryMessageTableDriven(t *testing.T) {
+	// Given
+	topics := Topics{Prefix: DefaultTopicPrefix, DiscoveryPrefix: DefaultDiscoveryPrefix}
+	timeSerie := &prom.LinkyTimeSerie{LinkyId: "XXXX", Prm: "12345678901234", ContractTypeName: "BASE"}
+	sensors := make(map[string]Sensor)
+	for _, sensor := range Sensors {
+		sensors[sensor.Key] = sensor
+	}
+
+	var tests = []struct {
+		key, deviceClass, stateClass, unit string
+	}{
+		{"energy_total", "energy", "total_increasing", "Wh"},
+		{"energy_index_2", "energy", "total_increasing", "Wh"},
+		{"power", "apparent_power", "measurement", "VA"},
+		{"intensity_p1", "current", "measurement", "A"},
+		{"voltage_p3", "voltage", "measurement", "V"},
+		{"tariff", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			// When
+			message, err := topics.DiscoveryMessage("house", timeSerie, sensors[tt.key])
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var config DiscoveryConfig
+			if err := json.Unmarshal(message.Payload, &config); err != nil {
+				t.Fatal(err)
+			}
+			if message.Topic != "homeassistant/sensor/linky_XXXX/"+tt.key+"/config" || !message.Retained {
+				t.Errorf("got topic %s retained %t", message.Topic, message.Retained)
+			}
+			if config.DeviceClass != tt.deviceClass || config.StateClass != tt.stateClass || config.Unit != tt.unit {
+				t.Errorf("got %+v", config)
+			}
+			if config.UniqueId != "linky_XXXX_"+tt.key || config.StateTopic != "linky/XXXX/state" {
+				t.Errorf("got %+v", config)
+			}
+			if len(config.Device.Identifiers) != 2 || config.Device.Identifiers[1] != "linky_prm_12345678901234" {
+				t.Errorf("got device %+v", config.Device)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package mqtt
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// Default topics prefixes
+const (
+	DefaultTopicPrefix     = "linky"
+	DefaultDiscoveryPrefix = "homeassistant"
+)
+
+// PublishTimeout bounds the wait for the broker acknowledgment of each message
+const PublishTimeout = 10 * time.Second
+
+// Availability payloads, the offline one is also the connection will
+const (
+	online  = "online"
+	offline = "offline"
+)
+
+// LinkyPublisher publishes each decoded frame of the meters to an MQTT broker with Home Assistant discovery
+type LinkyPublisher struct {
+	Broker   string // Broker URL, like tcp://localhost:1883
+	ClientId string
+	Username string
+	Password string
+	Topics   Topics
+
+	client     paho.Client
+	mutex      sync.Mutex
+	discovered map[string]bool // Discovery configs already published, by unique id
+}
+
+// Run connects to the broker and publishes the meters frames until the context is done.
+// Connection failures are retried in background.
+func (publisher *LinkyPublisher) Run(ctx context.Context, meters map[string]*core.LinkyConnector) {
+	publisher.discovered = make(map[string]bool)
+
+	options := paho.NewClientOptions().
+		AddBroker(publisher.Broker).
+		SetClientID(publisher.ClientId).
+		SetUsername(publisher.Username).
+		SetPassword(publisher.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(publisher.Topics.Availability(), offline, 1, true).
+		SetOnConnectHandler(publisher.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Error("Lost connection to MQTT broker", "broker", publisher.Broker, "error", err)
+		})
+	publisher.client = paho.NewClient(options)
+	publisher.client.Connect()
+
+	var wg sync.WaitGroup
+	for meter, connector := range meters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			frames := connector.Subscribe(1)
+			defer connector.Unsubscribe(frames)
+			publisher.watch(ctx, meter, frames)
+		}()
+	}
+	wg.Wait()
+
+	if publisher.client.IsConnectionOpen() {
+		publisher.publish(Message{Topic: publisher.Topics.Availability(), Payload: []byte(offline), Retained: true})
+	}
+	publisher.client.Disconnect(250)
+}
+
+// Announce availability and publish discovery configs again, the broker may have lost them
+func (publisher *LinkyPublisher) onConnect(_ paho.Client) {
+	slog.Info("Connected to MQTT broker", "broker", publisher.Broker)
+	publisher.mutex.Lock()
+	clear(publisher.discovered)
+	publisher.mutex.Unlock()
+	go publisher.publish(Message{Topic: publisher.Topics.Availability(), Payload: []byte(online), Retained: true})
+}
+
+// Publish each frame until the context is done or frames is closed
+func (publisher *LinkyPublisher) watch(ctx context.Context, meter string, frames <-chan *core.TicFrame) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			timeSerie := prom.ConvertFrameToTimeSerie(frame)
+			if timeSerie == nil {
+				continue
+			}
+			// Topics and Home Assistant unique ids are built on the meter address
+			if timeSerie.LinkyId == "" {
+				slog.Debug("Frame without meter address not published", "meter", meter)
+				continue
+			}
+			publisher.publishDiscovery(meter, timeSerie)
+			publisher.publishState(meter, timeSerie)
+		}
+	}
+}
+
+// Publish discovery configs of sensors sent by the meter and not announced yet
+func (publisher *LinkyPublisher) publishDiscovery(meter string, timeSerie *prom.LinkyTimeSerie) {
+	if publisher.Topics.DiscoveryPrefix == "" || !publisher.client.IsConnectionOpen() {
+		return
+	}
+
+	for _, sensor := range Sensors {
+		if sensor.Value(timeSerie) == nil {
+			continue
+		}
+		id := timeSerie.LinkyId + "_" + sensor.Key
+		publisher.mutex.Lock()
+		discovered := publisher.discovered[id]
+		publisher.mutex.Unlock()
+		if discovered {
+			continue
+		}
+
+		message, err := publisher.Topics.DiscoveryMessage(meter, timeSerie, sensor)
+		if err != nil {
+			slog.Error("Unable to build discovery config", "meter", meter, "sensor", sensor.Key, "error", err)
+			continue
+		}
+		if publisher.publish(message) {
+			publisher.mutex.Lock()
+			publisher.discovered[id] = true
+			publisher.mutex.Unlock()
+		}
+	}
+}
+
+// Publish the state of the meter, dropped while disconnected
+func (publisher *LinkyPublisher) publishState(meter string, timeSerie *prom.LinkyTimeSerie) {
+	if !publisher.client.IsConnectionOpen() {
+		return
+	}
+	message, err := publisher.Topics.StateMessage(meter, timeSerie)
+	if err != nil {
+		slog.Error("Unable to build state", "meter", meter, "error", err)
+		return
+	}
+	publisher.publish(message)
+}
+
+// Publish a message and wait for its acknowledgment
+func (publisher *LinkyPublisher) publish(message Message) bool {
+	token := publisher.client.Publish(message.Topic, 1, message.Retained, message.Payload)
+	if !token.WaitTimeout(PublishTimeout) {
+		slog.Error("MQTT publish timed out", "topic", message.Topic)
+		return false
+	}
+	if err := token.Error(); err != nil {
+		slog.Error("Unable to publish MQTT message", "topic", message.Topic, "error", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// MQTT 3.1.1 control packet types handled by the test broker
+const (
+	packetConnect    = 1
+	packetPublish    = 3
+	packetSubscribe  = 8
+	packetPingReq    = 12
+	packetDisconnect = 14
+)
+
+// Start a minimal MQTT 3.1.1 broker acknowledging connections and publications, published messages are
+// sent to the returned channel
+func serveBroker(t *testing.T) (string, <-chan Message) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	messages := make(chan Message, 1000)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleBrokerClient(conn, messages)
+		}
+	}()
+	return "tcp://" + listener.Addr().String(), messages
+}
+
+func handleBrokerClient(conn net.Conn, messages chan<- Message) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	for {
+		header, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			_, _ = conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		case packetPublish:
+			topicLength := int(binary.BigEndian.Uint16(body))
+			message := Message{Topic: string(body[2 : 2+topicLength]), Retained: header&0x01 == 1}
+			payload := body[2+topicLength:]
+			if qos := header >> 1 & 0x03; qos > 0 {
+				_, _ = conn.Write([]byte{0x40, 0x02, payload[0], payload[1]})
+				payload = payload[2:]
+			}
+			message.Payload = payload
+			messages <- message
+		case packetSubscribe:
+			_, _ = conn.Write([]byte{0x90, 0x03, body[0], body[1], 0x00})
+		case packetPingReq:
+			_, _ = conn.Write([]byte{0xd0, 0x00})
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// Serve historical frames, alternately without and with the meter address, until the test ends
+func serveFrames(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	frames := "\x02\nISOUSC 30 9\r\nPAPP 00800 )\r\x03" + "\x02\nADCO 021728123456 @\r\nISOUSC 30 9\r\nPAPP 00800 )\r\x03"
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			for {
+				if _, err := conn.Write([]byte(frames)); err != nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			_ = conn.Close()
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestPublisherWithBroker(t *testing.T) {
+	// Given
+	broker, messages := serveBroker(t)
+	connector := &core.LinkyConnector{Mode: core.Historical, Device: serveFrames(t)}
+	connector.Start()
+	defer connector.Stop()
+	publisher := LinkyPublisher{
+		Broker:   broker,
+		ClientId: "linky-exporter-test",
+		Topics:   Topics{Prefix: DefaultTopicPrefix, DiscoveryPrefix: DefaultDiscoveryPrefix},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	// When
+	go func() {
+		defer close(stopped)
+		publisher.Run(ctx, map[string]*core.LinkyConnector{"house": connector})
+	}()
+
+	// Then
+	published := make(map[string]Message)
+	timeout := time.After(5 * time.Second)
+	for published["linky/021728123456/state"].Topic == "" || len(published) < 4 {
+		select {
+		case message := <-messages:
+			published[message.Topic] = message
+		case <-timeout:
+			t.Fatalf("got messages %v", published)
+		}
+	}
+	cancel()
+	<-stopped
+	for len(messages) > 0 {
+		message := <-messages
+		published[message.Topic] = message
+	}
+
+	if availability := published["linky/status"]; string(availability.Payload) != offline || !availability.Retained {
+		t.Errorf("got availability %+v, want retained offline", availability)
+	}
+	power := published["homeassistant/sensor/linky_021728123456/power/config"]
+	if !power.Retained || !strings.Contains(string(power.Payload), `"state_topic":"linky/021728123456/state"`) {
+		t.Errorf("got power discovery %s", power.Payload)
+	}
+	if state := string(published["linky/021728123456/state"].Payload); !strings.Contains(state, `"power":800`) {
+		t.Errorf("got state %s", state)
+	}
+	for topic := range published {
+		if strings.Contains(topic, "//") || strings.Contains(topic, "linky__") {
+			t.Errorf("got topic %s of a frame without meter address", topic)
+		}
+	}
+}
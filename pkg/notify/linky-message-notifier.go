@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// WebhookTimeout bounds each webhook request
+const WebhookTimeout = 10 * time.Second
+
+// MessageEvent is sent when a meter message changes
+type MessageEvent struct {
+	Meter    string    `json:"meter"`
+	LinkyId  string    `json:"linky_id"`
+	Slot     string    `json:"slot"`
+	Text     string    `json:"text"`
+	Previous string    `json:"previous"`
+	Time     time.Time `json:"time"`
+}
+
+// MessageNotifier logs and posts to an optional webhook each change of the MSG1 and MSG2 meter messages
+type MessageNotifier struct {
+	Meter   string // Name of the watched meter
+	Webhook string // URL receiving MessageEvent as JSON, disabled if empty
+	client  http.Client
+}
+
+// Run watches the connector frames until the context is done
+func (notifier *MessageNotifier) Run(ctx context.Context, connector *core.LinkyConnector) {
+	frames := connector.Subscribe(1)
+	defer connector.Unsubscribe(frames)
+	notifier.Watch(ctx, frames)
+}
+
+// Message slots by group label
+var messageSlots = map[string]string{"MSG1": "1", "MSG2": "2"}
+
+// Watch frames until the context is done or frames is closed. The first message seen in each slot is the
+// reference, slots whose group is missing from a frame are left unchanged.
+func (notifier *MessageNotifier) Watch(ctx context.Context, frames <-chan *core.TicFrame) {
+	notifier.client.Timeout = WebhookTimeout
+
+	messages := make(map[string]string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			if frame.Standard == nil {
+				continue
+			}
+			current := map[string]string{"1": frame.Standard.Msg1, "2": frame.Standard.Msg2}
+			for _, group := range frame.Groups {
+				slot, found := messageSlots[group.Label]
+				if !found {
+					continue
+				}
+				text := current[slot]
+				if previous, known := messages[slot]; known && previous != text {
+					notifier.notify(ctx, MessageEvent{
+						Meter:    notifier.Meter,
+						LinkyId:  frame.Standard.Adsc,
+						Slot:     slot,
+						Text:     text,
+						Previous: previous,
+						Time:     frame.ReceivedAt,
+					})
+				}
+				messages[slot] = text
+			}
+		}
+	}
+}
+
+// Log the event and post it to the webhook
+func (notifier *MessageNotifier) notify(ctx context.Context, event MessageEvent) {
+	slog.Info("Meter message changed",
+		"meter", event.Meter,
+		"linky_id", event.LinkyId,
+		"slot", event.Slot,
+		"text", event.Text,
+		"previous", event.Previous)
+
+	if notifier.Webhook == "" {
+		return
+	}
+	err := notifier.post(ctx, event)
+	if err != nil {
+		slog.Error("Unable to send message webhook", "url", notifier.Webhook, "error", err)
+	}
+}
+
+func (notifier *MessageNotifier) post(ctx context.Context, event MessageEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, notifier.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := notifier.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestMessageNotifierWebhook(t *testing.T) {
+	// Given
+	events := make(chan MessageEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event MessageEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events <- event
+	}))
+	defer server.Close()
+
+	frames := make(chan *core.TicFrame, 4)
+	for _, message := range []string{"PAS DE MESSAGE", "PAS DE MESSAGE", "", "PM1 DEMAIN"} {
+		frame := &core.TicFrame{Standard: &core.StandardTicValue{Adsc: "XXXX", Msg1: message}, ReceivedAt: time.Now()}
+		// MSG2 is never sent, and MSG1 is missing from the third frame, dropped for a bad checksum
+		if message != "" {
+			frame.Groups = []core.TicGroup{{Label: "MSG1", Value: message}}
+		}
+		frames <- frame
+	}
+	close(frames)
+	notifier := MessageNotifier{Meter: "house", Webhook: server.URL}
+
+	// When
+	notifier.Watch(context.Background(), frames)
+
+	// Then
+	close(events)
+	var got []MessageEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	if got[0].Meter != "house" || got[0].Slot != "1" || got[0].Text != "PM1 DEMAIN" || got[0].Previous != "PAS DE MESSAGE" {
+		t.Errorf("got %+v", got[0])
+	}
+}
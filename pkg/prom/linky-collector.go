@@ -1,7 +1,10 @@
 package prom
 
 import (
+	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 
 	prometheus "github.com/prometheus/client_golang/prometheus"
 	"github.com/syberalexis/linky-exporter/pkg/core"
@@ -16,24 +19,31 @@ const (
 type MetricDef struct {
 	desc      *prometheus.Desc
 	valueType prometheus.ValueType
+	histogram bool // Sent as histogram, valueType is not used
 }
 
 // MetricCollector defines how to collect a specific metric
 type MetricCollector func(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie)
 
+// ConnectorMetricCollector defines how to collect a metric about the connector itself
+type ConnectorMetricCollector func(ch chan<- prometheus.Metric, lc *LinkyCollector)
+
 // LinkyCollector object to describe and collect metrics
 type LinkyCollector struct {
-	connector core.LinkyConnector
-	metrics   map[string]MetricDef
-	handlers  map[string]MetricCollector
+	connector         *core.LinkyConnector
+	metrics           map[string]MetricDef
+	handlers          map[string]MetricCollector
+	connectorHandlers map[string]ConnectorMetricCollector
+	frameTimestamps   bool // Frame metrics carry the frame reception time
 }
 
 // NewLinkyCollector method to construct LinkyCollector
 func NewLinkyCollector(connector *core.LinkyConnector) *LinkyCollector {
 	lc := &LinkyCollector{
-		connector: *connector,
-		metrics:   make(map[string]MetricDef),
-		handlers:  make(map[string]MetricCollector),
+		connector:         connector,
+		metrics:           make(map[string]MetricDef),
+		handlers:          make(map[string]MetricCollector),
+		connectorHandlers: make(map[string]ConnectorMetricCollector),
 	}
 
 	// Define all metrics
@@ -76,6 +86,24 @@ func NewLinkyCollector(connector *core.LinkyConnector) *LinkyCollector {
 	lc.registerMetric("linky_voltage_average", "Tension moyenne en V",
 		[]string{"linky_id", "phase"}, prometheus.GaugeValue, collectAverageVoltage)
 
+	lc.registerMetric("linky_power_max_timestamp_seconds", "Horodate de la puissance apparente maximale",
+		[]string{"linky_id", "mode", "phase"}, prometheus.GaugeValue, collectPowerMaxTimestamp)
+
+	lc.registerMetric("linky_power_last_year_timestamp_seconds", "Horodate de la puissance apparente maximale n-1",
+		[]string{"linky_id", "mode", "phase"}, prometheus.GaugeValue, collectPowerLastYearTimestamp)
+
+	lc.registerMetric("linky_load_curve_point_timestamp_seconds", "Horodate du point de courbe de charge",
+		[]string{"linky_id", "mode"}, prometheus.GaugeValue, collectLoadCurvePointTimestamp)
+
+	lc.registerMetric("linky_load_curve_point_last_year_timestamp_seconds", "Horodate du point de courbe de charge n-1",
+		[]string{"linky_id", "mode"}, prometheus.GaugeValue, collectLoadCurvePointLastYearTimestamp)
+
+	lc.registerMetric("linky_voltage_average_timestamp_seconds", "Horodate de la tension moyenne",
+		[]string{"linky_id", "phase"}, prometheus.GaugeValue, collectAverageVoltageTimestamp)
+
+	lc.registerMetric("linky_movable_peak_timestamp_seconds", "Horodate de la pointe mobile",
+		[]string{"linky_id", "type", "phase"}, prometheus.GaugeValue, collectMovablePeakTimestamp)
+
 	lc.registerMetric("linky_status", "status from registry",
 		[]string{"linky_id", "name"}, prometheus.GaugeValue, collectStatus)
 
@@ -85,13 +113,65 @@ func NewLinkyCollector(connector *core.LinkyConnector) *LinkyCollector {
 	lc.registerMetric("linky_relay", "Etat du relai",
 		[]string{"linky_id", "id"}, prometheus.GaugeValue, collectRelay)
 
+	lc.registerMetric("linky_intensity_max", "Intensité maximale appelée en A",
+		[]string{"linky_id", "phase"}, prometheus.GaugeValue, collectIntensityMax)
+
+	lc.registerMetric("linky_power_max_three_phase", "Puissance maximale triphasée atteinte en W",
+		[]string{"linky_id"}, prometheus.GaugeValue, collectPowerMaxThreePhase)
+
+	lc.registerMetric("linky_overload_intensity", "Avertissement de dépassement de puissance souscrite en A",
+		[]string{"linky_id"}, prometheus.GaugeValue, collectOverloadIntensity)
+
+	lc.registerMetric("linky_ejp_notice_minutes", "Préavis début EJP en minutes",
+		[]string{"linky_id"}, prometheus.GaugeValue, collectEJPNotice)
+
+	lc.registerMetric("linky_tariff_day_color", "Couleur du jour et du lendemain pour les contrats tempo et EJP",
+		[]string{"linky_id", "day", "color"}, prometheus.GaugeValue, collectTariffDayColor)
+
+	lc.registerMetric("linky_schedule_info", "Horaire heures pleines heures creuses",
+		[]string{"linky_id", "group"}, prometheus.GaugeValue, collectScheduleInfo)
+
+	lc.registerMetric("linky_phase_potential_present", "Présence du potentiel de la phase",
+		[]string{"linky_id", "phase"}, prometheus.GaugeValue, collectPhasePotential)
+
+	lc.registerMetric("linky_state_word_bit", "Bit du mot d'état du compteur",
+		[]string{"linky_id", "bit"}, prometheus.GaugeValue, collectStateWord)
+
+	lc.registerMetric("linky_message_info", "Message court et ultra court du compteur",
+		[]string{"linky_id", "slot", "text"}, prometheus.GaugeValue, collectMessage)
+
 	lc.registerMetric(
 		"linky_provider_day_info",
-		"Numéro du jour en cours, du prochain jour et de son profil",
-		[]string{"linky_id", "prm", "current_day", "next_day", "next_day_profile"},
+		"Numéro de l'index tarifaire en cours, du jour en cours, du prochain jour et de son profil",
+		[]string{"linky_id", "prm", "current_index", "current_day", "next_day", "next_day_profile"},
 		prometheus.GaugeValue,
 		collectProviderDayInfo)
 
+	lc.registerMetric("linky_next_day_switch_start_seconds",
+		"Heure de début en secondes après minuit d'un point de commutation du prochain jour",
+		[]string{"linky_id", "point", "index", "action"}, prometheus.GaugeValue, collectNextDaySwitch)
+
+	lc.registerConnectorMetric("linky_frame_checksum_errors_total", "Groupes rejetés pour checksum invalide",
+		[]string{"label"}, prometheus.CounterValue, collectChecksumErrors)
+
+	lc.registerConnectorMetric("linky_up", "Trame reçue récemment du compteur",
+		[]string{}, prometheus.GaugeValue, collectUp)
+
+	lc.registerConnectorMetric("linky_exporter_frames_total", "Nombre de trames lues",
+		[]string{}, prometheus.CounterValue, collectFrames)
+
+	lc.registerConnectorMetric("linky_exporter_frame_errors_total", "Nombre d'erreurs de lecture par raison",
+		[]string{"reason"}, prometheus.CounterValue, collectFrameErrors)
+
+	lc.registerConnectorMetric("linky_exporter_last_frame_timestamp_seconds", "Timestamp de la dernière trame reçue",
+		[]string{}, prometheus.GaugeValue, collectLastFrameTimestamp)
+
+	lc.registerConnectorHistogram("linky_exporter_frame_read_duration_seconds", "Durée de lecture d'une trame en secondes",
+		[]string{}, collectFrameReadDuration)
+
+	lc.registerConnectorMetric("linky_exporter_unknown_labels_total", "Groupes ignorés pour étiquette inconnue",
+		[]string{"label"}, prometheus.CounterValue, collectUnknownLabels)
+
 	return lc
 }
 
@@ -109,6 +189,49 @@ func (lc *LinkyCollector) registerMetric(
 	lc.handlers[name] = handler
 }
 
+// registerConnectorMetric adds a new metric definition collected even without any frame
+func (lc *LinkyCollector) registerConnectorMetric(
+	name,
+	help string,
+	labels []string,
+	valueType prometheus.ValueType,
+	handler ConnectorMetricCollector) {
+	lc.metrics[name] = MetricDef{
+		desc:      prometheus.NewDesc(name, help, labels, nil),
+		valueType: valueType,
+	}
+	lc.connectorHandlers[name] = handler
+}
+
+// registerConnectorHistogram adds a new histogram definition collected even without any frame
+func (lc *LinkyCollector) registerConnectorHistogram(
+	name,
+	help string,
+	labels []string,
+	handler ConnectorMetricCollector) {
+	lc.metrics[name] = MetricDef{
+		desc:      prometheus.NewDesc(name, help, labels, nil),
+		histogram: true,
+	}
+	lc.connectorHandlers[name] = handler
+}
+
+// Disable stops collecting a metric by name
+func (lc *LinkyCollector) Disable(name string) error {
+	if _, found := lc.metrics[name]; !found {
+		return fmt.Errorf("unknown metric %s", name)
+	}
+	delete(lc.metrics, name)
+	delete(lc.handlers, name)
+	delete(lc.connectorHandlers, name)
+	return nil
+}
+
+// IsConnectorMetric checks if a metric is about the connector itself rather than the last frame values
+func IsConnectorMetric(name string) bool {
+	return strings.HasPrefix(name, "linky_exporter_") || name == "linky_up" || name == "linky_frame_checksum_errors_total"
+}
+
 // Describe implements required describe function for all prometheus collectors
 func (lc *LinkyCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range lc.metrics {
@@ -118,49 +241,61 @@ func (lc *LinkyCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements required collect function for all prometheus collectors
 func (lc *LinkyCollector) Collect(ch chan<- prometheus.Metric) {
-	var timeSerie *LinkyTimeSerie
-	var err error
-
-	switch lc.connector.Mode {
-	case core.Standard:
-		var ticValues *core.StandardTicValue
-		ticValues, err = lc.connector.GetLastStandardTicValue()
-		if err == nil {
-			timeSerie = ConvertStandardTicValueToTimeSerie(ticValues)
-		}
-	case core.Historical:
-		var ticValues *core.HistoricalTicValue
-		ticValues, err = lc.connector.GetLastHistoricalTicValue()
-		if err == nil {
-			timeSerie = ConvertHistoricalTicValueToTimeSerie(ticValues)
-		}
-	default:
-		slog.Error("Unable to read telemetry information", "error", err)
-		return
+	// Collect connector metrics, available even without any frame
+	for _, handler := range lc.connectorHandlers {
+		handler(ch, lc)
 	}
 
-	if err != nil {
-		slog.Error("Unable to read telemetry information", "error", err)
+	// Mode of the last frame, the connector mode may still be detected in background
+	frame := lc.connector.LastFrame()
+	if frame == nil {
+		slog.Error("Unable to read telemetry information", "device", lc.connector.Device, "error", "no frame received yet")
+		return
+	}
+	timeSerie := ConvertFrameToTimeSerie(frame)
+	if timeSerie == nil {
+		slog.Error("Unable to read telemetry information", "device", lc.connector.Device, "error", "frame without values")
 		return
 	}
+	standard := frame.Standard != nil
+
+	// Metrics of the frame are collected from a single snapshot, timestamped with its reception time
+	if lc.frameTimestamps {
+		metrics := make(chan prometheus.Metric)
+		sent := make(chan struct{})
+		go func(out chan<- prometheus.Metric) {
+			defer close(sent)
+			for metric := range metrics {
+				out <- prometheus.NewMetricWithTimestamp(timeSerie.ReceivedAt, metric)
+			}
+		}(ch)
+		defer func() {
+			close(metrics)
+			<-sent
+		}()
+		ch = metrics
+	}
 
 	// Collect all metrics
 	for name, handler := range lc.handlers {
 		// Skip standard-only metrics for historical mode
-		if lc.connector.Mode != core.Standard &&
+		if !standard &&
 			(name == "linky_voltage" || name == "linky_status" ||
 				name == "linky_relay" || name == "linky_movable_peak" ||
-				name == "linky_provider_day_info") {
+				name == "linky_provider_day_info" || name == "linky_next_day_switch_start_seconds") {
 			continue
 		}
 
-		// Skip movable peak if not available
-		if name == "linky_movable_peak" && timeSerie.MovingPeakStart1 == 0 {
+		// Skip historical-only metrics for standard mode
+		if standard &&
+			(name == "linky_intensity_max" || name == "linky_power_max_three_phase" ||
+				name == "linky_overload_intensity" || name == "linky_ejp_notice_minutes" ||
+				name == "linky_schedule_info") {
 			continue
 		}
 
-		// Skip provider day info (commented out in original code)
-		if name == "linky_provider_day_info" {
+		// Skip movable peak if not available
+		if name == "linky_movable_peak" && timeSerie.MovingPeakStart1 == 0 {
 			continue
 		}
 
@@ -192,6 +327,20 @@ func sendMetricIfNonZero(
 	}
 }
 
+// sendMetricIfSent sends a metric to the channel only if its field has been sent by the meter, even at zero
+func sendMetricIfSent(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	metricType prometheus.ValueType,
+	ts *LinkyTimeSerie,
+	field string,
+	value float64,
+	labelValues ...string) {
+	if ts.Sent(field) {
+		sendMetric(ch, desc, metricType, value, labelValues...)
+	}
+}
+
 // Metric collector implementations
 func collectLinkyDate(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
 	metric := lc.metrics["linky_timestamp"]
@@ -260,8 +409,8 @@ func collectReactiveEnergyTotal(ch chan<- prometheus.Metric, lc *LinkyCollector,
 func collectIntensity(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
 	metric := lc.metrics["linky_intensity"]
 	sendMetric(ch, metric.desc, metric.valueType, ts.IntensityP1, ts.LinkyId, "1")
-	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.IntensityP2, ts.LinkyId, "2")
-	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.IntensityP3, ts.LinkyId, "3")
+	sendMetricIfSent(ch, metric.desc, metric.valueType, ts, "IntensityP2", ts.IntensityP2, ts.LinkyId, "2")
+	sendMetricIfSent(ch, metric.desc, metric.valueType, ts, "IntensityP3", ts.IntensityP3, ts.LinkyId, "3")
 }
 
 func collectVoltage(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
@@ -356,6 +505,65 @@ func collectAverageVoltage(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *
 	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.AverageVoltageP3, ts.LinkyId, "3")
 }
 
+func collectPowerMaxTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_power_max_timestamp_seconds"]
+
+	metrics := []struct {
+		value float64
+		mode  string
+		phase string
+	}{
+		{ts.PowerUsedMaxTime, USED, "1"},
+		{ts.PowerUsedMaxP1Time, USED, "1"},
+		{ts.PowerUsedMaxP2Time, USED, "2"},
+		{ts.PowerUsedMaxP3Time, USED, "3"},
+		{ts.PowerProducedMaxTime, PRODUCED, "0"},
+	}
+
+	for _, m := range metrics {
+		sendMetricIfNonZero(ch, metric.desc, metric.valueType, m.value, ts.LinkyId, m.mode, m.phase)
+	}
+}
+
+func collectPowerLastYearTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_power_last_year_timestamp_seconds"]
+
+	metrics := []struct {
+		value float64
+		mode  string
+		phase string
+	}{
+		{ts.PowerUsedMaxLastYearTime, USED, "1"},
+		{ts.PowerUsedMaxLastYearP1Time, USED, "1"},
+		{ts.PowerUsedMaxLastYearP2Time, USED, "2"},
+		{ts.PowerUsedMaxLastYearP3Time, USED, "3"},
+		{ts.PowerProducedLastYearTime, PRODUCED, "0"},
+	}
+
+	for _, m := range metrics {
+		sendMetricIfNonZero(ch, metric.desc, metric.valueType, m.value, ts.LinkyId, m.mode, m.phase)
+	}
+}
+
+func collectLoadCurvePointTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_load_curve_point_timestamp_seconds"]
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.UsedLoadCurvePointTime, ts.LinkyId, USED)
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.ProducedLoadCurvePointTime, ts.LinkyId, PRODUCED)
+}
+
+func collectLoadCurvePointLastYearTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_load_curve_point_last_year_timestamp_seconds"]
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.UsedLoadCurvePointLastYearTime, ts.LinkyId, USED)
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.ProducedLoadCurvePointLastYearTime, ts.LinkyId, PRODUCED)
+}
+
+func collectAverageVoltageTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_voltage_average_timestamp_seconds"]
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.AverageVoltageP1Time, ts.LinkyId, "1")
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.AverageVoltageP2Time, ts.LinkyId, "2")
+	sendMetricIfNonZero(ch, metric.desc, metric.valueType, ts.AverageVoltageP3Time, ts.LinkyId, "3")
+}
+
 func collectStatus(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
 	metric := lc.metrics["linky_status"]
 
@@ -409,6 +617,27 @@ func collectMovablePeak(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *Lin
 	}
 }
 
+func collectMovablePeakTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_movable_peak_timestamp_seconds"]
+
+	peakMetrics := []struct {
+		value float64
+		type_ string
+		phase string
+	}{
+		{ts.MovingPeakStart1Time, "start", "1"},
+		{ts.MovingPeakEnd1Time, "end", "1"},
+		{ts.MovingPeakStart2Time, "start", "2"},
+		{ts.MovingPeakEnd2Time, "end", "2"},
+		{ts.MovingPeakStart3Time, "start", "3"},
+		{ts.MovingPeakEnd3Time, "end", "3"},
+	}
+
+	for _, pm := range peakMetrics {
+		sendMetricIfNonZero(ch, metric.desc, metric.valueType, pm.value, ts.LinkyId, pm.type_, pm.phase)
+	}
+}
+
 func collectRelay(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
 	metric := lc.metrics["linky_relay"]
 
@@ -431,9 +660,154 @@ func collectRelay(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTime
 	}
 }
 
+func collectIntensityMax(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_intensity_max"]
+	sendMetricIfSent(ch, metric.desc, metric.valueType, ts, "IntensityMaxP1", ts.IntensityMaxP1, ts.LinkyId, "1")
+	sendMetricIfSent(ch, metric.desc, metric.valueType, ts, "IntensityMaxP2", ts.IntensityMaxP2, ts.LinkyId, "2")
+	sendMetricIfSent(ch, metric.desc, metric.valueType, ts, "IntensityMaxP3", ts.IntensityMaxP3, ts.LinkyId, "3")
+}
+
+func collectPowerMaxThreePhase(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_power_max_three_phase"]
+	sendMetricIfSent(ch, metric.desc, metric.valueType, ts, "PowerUsedMaxThreePhase", ts.PowerUsedMaxThreePhase, ts.LinkyId)
+}
+
+func collectOverloadIntensity(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	// ADPS is only sent by single-phase meters while overloaded, absent means 0
+	if ts.ThreePhase {
+		return
+	}
+	metric := lc.metrics["linky_overload_intensity"]
+	sendMetric(ch, metric.desc, metric.valueType, ts.OverloadIntensity, ts.LinkyId)
+}
+
+func collectEJPNotice(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	// PEJP is only sent during the 30 minutes before a peak period, absent means 0
+	if !strings.HasPrefix(ts.ContractTypeName, "EJP") {
+		return
+	}
+	metric := lc.metrics["linky_ejp_notice_minutes"]
+	sendMetric(ch, metric.desc, metric.valueType, ts.EJPNotice, ts.LinkyId)
+}
+
+func collectTariffDayColor(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	if ts.TodayColor == "" {
+		return
+	}
+	metric := lc.metrics["linky_tariff_day_color"]
+
+	days := []struct {
+		color string
+		day   string
+	}{
+		{ts.TodayColor, "today"},
+		{ts.TomorrowColor, "tomorrow"},
+	}
+
+	for _, d := range days {
+		for _, color := range []string{ColorBlue, ColorWhite, ColorRed, ColorUnknown} {
+			value := 0.0
+			if d.color == color {
+				value = 1
+			}
+			sendMetric(ch, metric.desc, metric.valueType, value, ts.LinkyId, d.day, color)
+		}
+	}
+}
+
+func collectScheduleInfo(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	if ts.ScheduleGroup == "" {
+		return
+	}
+	metric := lc.metrics["linky_schedule_info"]
+	sendMetric(ch, metric.desc, metric.valueType, 1, ts.LinkyId, ts.ScheduleGroup)
+}
+
+func collectPhasePotential(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_phase_potential_present"]
+	for i, value := range ts.PhasePotentials {
+		sendMetric(ch, metric.desc, metric.valueType, value, ts.LinkyId, strconv.Itoa(i+1))
+	}
+}
+
+func collectStateWord(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_state_word_bit"]
+	for bit, value := range ts.StateWordBits {
+		sendMetric(ch, metric.desc, metric.valueType, value, ts.LinkyId, strconv.Itoa(bit))
+	}
+}
+
+func collectMessage(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_message_info"]
+	if ts.Message1 != "" {
+		sendMetric(ch, metric.desc, metric.valueType, 1, ts.LinkyId, "1", ts.Message1)
+	}
+	if ts.Message2 != "" {
+		sendMetric(ch, metric.desc, metric.valueType, 1, ts.LinkyId, "2", ts.Message2)
+	}
+}
+
 func collectProviderDayInfo(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
 	metric := lc.metrics["linky_provider_day_info"]
 	sendMetric(ch, metric.desc, metric.valueType, 1,
-		ts.LinkyId, ts.Prm, ts.ContractTypeDayNumber,
+		ts.LinkyId, ts.Prm, ts.CurrentPricingNumber, ts.ContractTypeDayNumber,
 		ts.ContractTypeNextDayNumber, ts.ContractTypeNextDayProfile)
 }
+
+func collectNextDaySwitch(ch chan<- prometheus.Metric, lc *LinkyCollector, ts *LinkyTimeSerie) {
+	metric := lc.metrics["linky_next_day_switch_start_seconds"]
+	for i, daySwitch := range ts.NextDaySwitches {
+		sendMetric(ch, metric.desc, metric.valueType, float64(daySwitch.Minutes()*60),
+			ts.LinkyId, strconv.Itoa(i+1), strconv.Itoa(int(daySwitch.Index)), fmt.Sprintf("%04X", daySwitch.Action))
+	}
+}
+
+func collectChecksumErrors(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	metric := lc.metrics["linky_frame_checksum_errors_total"]
+	for label, count := range lc.connector.Stats().ChecksumErrors() {
+		sendMetric(ch, metric.desc, metric.valueType, float64(count), label)
+	}
+}
+
+func collectUp(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	metric := lc.metrics["linky_up"]
+	up := 0.0
+	if lc.connector.IsUp() {
+		up = 1
+	}
+	sendMetric(ch, metric.desc, metric.valueType, up)
+}
+
+func collectFrames(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	metric := lc.metrics["linky_exporter_frames_total"]
+	sendMetric(ch, metric.desc, metric.valueType, float64(lc.connector.Stats().Frames()))
+}
+
+func collectFrameErrors(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	metric := lc.metrics["linky_exporter_frame_errors_total"]
+	for reason, count := range lc.connector.Stats().FrameErrors() {
+		sendMetric(ch, metric.desc, metric.valueType, float64(count), reason)
+	}
+}
+
+func collectLastFrameTimestamp(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	frame := lc.connector.LastFrame()
+	if frame == nil {
+		return
+	}
+	metric := lc.metrics["linky_exporter_last_frame_timestamp_seconds"]
+	sendMetric(ch, metric.desc, metric.valueType, float64(frame.ReceivedAt.UnixNano())/1e9)
+}
+
+func collectFrameReadDuration(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	metric := lc.metrics["linky_exporter_frame_read_duration_seconds"]
+	histogram := lc.connector.Stats().ReadDurations()
+	ch <- prometheus.MustNewConstHistogram(metric.desc, histogram.Count, histogram.Sum, histogram.Buckets)
+}
+
+func collectUnknownLabels(ch chan<- prometheus.Metric, lc *LinkyCollector) {
+	metric := lc.metrics["linky_exporter_unknown_labels_total"]
+	for label, count := range lc.connector.Stats().UnknownLabels() {
+		sendMetric(ch, metric.desc, metric.valueType, float64(count), label)
+	}
+}
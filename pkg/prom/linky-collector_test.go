@@ -0,0 +1,107 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestCollectedTypesMatchDefinitions(t *testing.T) {
+	// Given
+	collector := NewLinkyCollector(&core.LinkyConnector{})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	// When
+	families, err := registry.Gather()
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(families) == 0 {
+		t.Fatal("no metric collected")
+	}
+	for _, family := range families {
+		definition := collector.metrics[family.GetName()]
+		expected := map[prometheus.ValueType]dto.MetricType{
+			prometheus.CounterValue: dto.MetricType_COUNTER,
+			prometheus.GaugeValue:   dto.MetricType_GAUGE,
+			prometheus.UntypedValue: dto.MetricType_UNTYPED,
+		}[definition.valueType]
+		if definition.histogram {
+			expected = dto.MetricType_HISTOGRAM
+		}
+		if family.GetType() != expected {
+			t.Errorf("%s: got type %s, want %s", family.GetName(), family.GetType(), expected)
+		}
+	}
+}
+
+func TestEveryMetricBelongsToOneGroup(t *testing.T) {
+	// Given
+	collector := NewLinkyCollector(&core.LinkyConnector{})
+	groups := make(map[string]string)
+	for group, names := range MetricGroups {
+		for _, name := range names {
+			if other, found := groups[name]; found {
+				t.Errorf("%s is in groups %s and %s", name, other, group)
+			}
+			groups[name] = group
+		}
+	}
+
+	for name := range collector.metrics {
+		// When
+		_, found := groups[name]
+
+		// Then
+		if !found && name != "linky_up" {
+			t.Errorf("%s is in no group", name)
+		}
+	}
+	for name := range groups {
+		if _, found := collector.metrics[name]; !found {
+			t.Errorf("%s of group %s is not a metric", name, groups[name])
+		}
+	}
+}
+
+func TestCollectPhasesTableDriven(t *testing.T) {
+	// Given
+	collector := NewLinkyCollector(&core.LinkyConnector{})
+	var tests = []struct {
+		name  string
+		frame *core.TicFrame
+		want  map[string]int // Number of series by metric
+	}{
+		{"single-phase", singlePhaseFrame, map[string]int{
+			"linky_intensity": 1, "linky_intensity_max": 1, "linky_power_max_three_phase": 0, "linky_overload_intensity": 1,
+		}},
+		{"idle three-phase", idleThreePhaseFrame, map[string]int{
+			"linky_intensity": 3, "linky_intensity_max": 3, "linky_power_max_three_phase": 1, "linky_overload_intensity": 0,
+		}},
+		{"loaded three-phase", loadedThreePhaseFrame, map[string]int{
+			"linky_intensity": 3, "linky_intensity_max": 3, "linky_power_max_three_phase": 1, "linky_overload_intensity": 0,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := ConvertFrameToTimeSerie(tt.frame)
+			for name, want := range tt.want {
+				// When
+				ch := make(chan prometheus.Metric, 10)
+				collector.handlers[name](ch, collector, ts)
+				close(ch)
+
+				// Then
+				if len(ch) != want {
+					t.Errorf("%s: got %d series, want %d", name, len(ch), want)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,151 @@
 package prom
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/syberalexis/linky-exporter/pkg/core"
 )
 
+// Tariff day colors of Tempo and EJP contracts
+const (
+	ColorBlue    = "blue"
+	ColorWhite   = "white"
+	ColorRed     = "red"
+	ColorUnknown = "unknown"
+)
+
+// Tempo colors from PTEC and DEMAIN codes
+var historicalTempoColors = map[string]string{
+	"HCJB": ColorBlue,
+	"HPJB": ColorBlue,
+	"HCJW": ColorWhite,
+	"HPJW": ColorWhite,
+	"HCJR": ColorRed,
+	"HPJR": ColorRed,
+	"BLEU": ColorBlue,
+	"BLAN": ColorWhite,
+	"ROUG": ColorRed,
+}
+
+// Tempo color of a PTEC or DEMAIN code, unknown when not recognized
+func historicalTempoColor(code string) string {
+	color, ok := historicalTempoColors[code]
+	if !ok {
+		return ColorUnknown
+	}
+	return color
+}
+
+// Unix time in seconds of an horodate, 0 when the group has not been received
+func unixSeconds(date core.Horodate) float64 {
+	if date.IsZero() {
+		return 0
+	}
+	return float64(date.Unix())
+}
+
+// Tempo colors from the STGE status bits
+var standardTempoColors = []string{ColorUnknown, ColorBlue, ColorWhite, ColorRed}
+
+// Time serie fields decoded from the standard mode labels
+var standardSerieFields = map[string][]string{
+	"DATE":   {"LinkyDate"},
+	"EAST":   {"TotalEnergyUsed"},
+	"EAIT":   {"TotalEnergyProduced"},
+	"PREF":   {"ReferencePower"},
+	"PCOUP":  {"BreakingPower"},
+	"SINSTS": {"PowerUsed"},
+	"SINSTI": {"PowerProduced"},
+	"STGE": {
+		"DryContactStatus", "CutOffDeviceStatus", "LinkyTerminalShieldStatus", "SurgeStatus",
+		"ReferencePowerExceededStatus", "ConsumptionStatus", "EnergyDirectionStatus", "ContractTypePriceStatus",
+		"ContractTypePriceDistributorStatus", "ClockStatus", "TicStatus", "EuridisLinkStatus", "CPLStatus",
+		"CPLSyncStatus", "TempoContractColorStatus", "TempoContractNextDayColorStatus", "MovingPeakNoticeStatus",
+		"MovingPeakStatus",
+	},
+	"RELAIS":   {"Relay1", "Relay2", "Relay3", "Relay4", "Relay5", "Relay6", "Relay7", "Relay8"},
+	"SMAXSN":   {"PowerUsedMax", "PowerUsedMaxTime"},
+	"SMAXSN-1": {"PowerUsedMaxLastYear", "PowerUsedMaxLastYearTime"},
+	"SMAXIN":   {"PowerProducedMax", "PowerProducedMaxTime"},
+	"SMAXIN-1": {"PowerProducedLastYear", "PowerProducedLastYearTime"},
+	"CCASN":    {"UsedLoadCurvePoint", "UsedLoadCurvePointTime"},
+	"CCASN-1":  {"UsedLoadCurvePointLastYear", "UsedLoadCurvePointLastYearTime"},
+	"CCAIN":    {"ProducedLoadCurvePoint", "ProducedLoadCurvePointTime"},
+	"CCAIN-1":  {"ProducedLoadCurvePointLastYear", "ProducedLoadCurvePointLastYearTime"},
+}
+
+// Time serie fields decoded from the historical mode labels
+var historicalSerieFields = map[string][]string{
+	"ISOUSC":  {"ReferencePower"},
+	"BASE":    {"EnergyUsedIndex1"},
+	"HCHC":    {"EnergyUsedIndex1", "TotalEnergyUsed"},
+	"HCHP":    {"EnergyUsedIndex2", "TotalEnergyUsed"},
+	"EJPHN":   {"EnergyUsedIndex1"},
+	"EJPHPM":  {"EnergyUsedIndex2"},
+	"BBRHCJB": {"EnergyUsedIndex1"},
+	"BBRHPJB": {"EnergyUsedIndex2"},
+	"BBRHCJW": {"EnergyUsedIndex3"},
+	"BBRHPJW": {"EnergyUsedIndex4"},
+	"BBRHCJR": {"EnergyUsedIndex5"},
+	"BBRHPJR": {"EnergyUsedIndex6"},
+	"PEJP":    {"EJPNotice"},
+	"IINST":   {"IntensityP1"},
+	"IMAX":    {"IntensityMaxP1"},
+	"ADPS":    {"OverloadIntensity"},
+	"PMAX":    {"PowerUsedMaxThreePhase"},
+	"PAPP":    {"PowerUsed"},
+}
+
+func init() {
+	for i := 1; i <= 10; i++ {
+		standardSerieFields[fmt.Sprintf("EASF%02d", i)] = []string{fmt.Sprintf("EnergyUsedIndex%d", i)}
+	}
+	for i := 1; i <= 4; i++ {
+		standardSerieFields[fmt.Sprintf("EASD%02d", i)] = []string{fmt.Sprintf("EnergyUsedDistributorIndex%d", i)}
+		standardSerieFields[fmt.Sprintf("ERQ%d", i)] = []string{fmt.Sprintf("TotalReactiveEnergyQ%d", i)}
+	}
+	for phase := 1; phase <= 3; phase++ {
+		standardSerieFields[fmt.Sprintf("IRMS%d", phase)] = []string{fmt.Sprintf("IntensityP%d", phase)}
+		standardSerieFields[fmt.Sprintf("URMS%d", phase)] = []string{fmt.Sprintf("VoltageP%d", phase)}
+		standardSerieFields[fmt.Sprintf("SINSTS%d", phase)] = []string{fmt.Sprintf("PowerUsedP%d", phase)}
+		standardSerieFields[fmt.Sprintf("SMAXSN%d", phase)] = []string{fmt.Sprintf("PowerUsedMaxP%d", phase), fmt.Sprintf("PowerUsedMaxP%dTime", phase)}
+		standardSerieFields[fmt.Sprintf("SMAXSN%d-1", phase)] = []string{fmt.Sprintf("PowerUsedMaxLastYearP%d", phase), fmt.Sprintf("PowerUsedMaxLastYearP%dTime", phase)}
+		standardSerieFields[fmt.Sprintf("UMOY%d", phase)] = []string{fmt.Sprintf("AverageVoltageP%d", phase), fmt.Sprintf("AverageVoltageP%dTime", phase)}
+		standardSerieFields[fmt.Sprintf("DPM%d", phase)] = []string{fmt.Sprintf("MovingPeakStart%d", phase), fmt.Sprintf("MovingPeakStart%dTime", phase)}
+		standardSerieFields[fmt.Sprintf("FPM%d", phase)] = []string{fmt.Sprintf("MovingPeakEnd%d", phase), fmt.Sprintf("MovingPeakEnd%dTime", phase)}
+		historicalSerieFields[fmt.Sprintf("IINST%d", phase)] = []string{fmt.Sprintf("IntensityP%d", phase)}
+		historicalSerieFields[fmt.Sprintf("IMAX%d", phase)] = []string{fmt.Sprintf("IntensityMaxP%d", phase)}
+	}
+}
+
+// ConvertFrameToTimeSerie converts the values of a frame and records the fields decoded from its groups,
+// nil if the frame has no values
+func ConvertFrameToTimeSerie(frame *core.TicFrame) *LinkyTimeSerie {
+	var timeSerie *LinkyTimeSerie
+	var serieFields map[string][]string
+	switch {
+	case frame.Standard != nil:
+		timeSerie = ConvertStandardTicValueToTimeSerie(frame.Standard)
+		serieFields = standardSerieFields
+	case frame.Historical != nil:
+		timeSerie = ConvertHistoricalTicValueToTimeSerie(frame.Historical)
+		serieFields = historicalSerieFields
+	default:
+		return nil
+	}
+
+	timeSerie.ReceivedAt = frame.ReceivedAt
+	timeSerie.sent = make(map[string]bool)
+	for _, group := range frame.Groups {
+		for _, field := range serieFields[strings.ToUpper(group.Label)] {
+			timeSerie.sent[field] = true
+		}
+	}
+	return timeSerie
+}
+
 // Convert (with construction) Historical Tic Value to Time serie value
 func ConvertHistoricalTicValueToTimeSerie(historicalValues *core.HistoricalTicValue) *LinkyTimeSerie {
 	timeSerie := &LinkyTimeSerie{
@@ -14,9 +154,9 @@ func ConvertHistoricalTicValueToTimeSerie(historicalValues *core.HistoricalTicVa
 		ContractTypeName: historicalValues.Optarif,
 		PriceLabel:       historicalValues.Ptec,
 		PowerUsed:        float64(historicalValues.Papp),
+		ThreePhase:       historicalValues.ThreePhase,
 	}
 
-	isTriplePhase := historicalValues.Iinst2 != 0 || historicalValues.Iinst3 != 0
 	isBase := historicalValues.Base != 0
 	isHCHP := historicalValues.Hchc != 0 || historicalValues.Hchp != 0
 	isEJP := historicalValues.Ejphn != 0 || historicalValues.Ejphpn != 0
@@ -24,17 +164,24 @@ func ConvertHistoricalTicValueToTimeSerie(historicalValues *core.HistoricalTicVa
 		historicalValues.Bbrhcjw != 0 || historicalValues.Bbrhpjw != 0 ||
 		historicalValues.Bbrhcjr != 0 || historicalValues.Bbrhpjr != 0
 
-	if isTriplePhase {
+	if historicalValues.ThreePhase {
 		timeSerie.ReferencePower = float64(historicalValues.Isousc) * 3 * 200 / 1000
 		timeSerie.IntensityP1 = float64(historicalValues.Iinst1)
 		timeSerie.IntensityP2 = float64(historicalValues.Iinst2)
 		timeSerie.IntensityP3 = float64(historicalValues.Iinst3)
+		timeSerie.IntensityMaxP1 = float64(historicalValues.Imax1)
+		timeSerie.IntensityMaxP2 = float64(historicalValues.Imax2)
+		timeSerie.IntensityMaxP3 = float64(historicalValues.Imax3)
+		timeSerie.PowerUsedMaxThreePhase = float64(historicalValues.Pmax)
 	} else {
 		timeSerie.ReferencePower = float64(historicalValues.Isousc) * 200 / 1000
 		timeSerie.IntensityP1 = float64(historicalValues.Iinst)
-		timeSerie.BreakingPower = float64(historicalValues.Adps) * 200 / 1000
+		timeSerie.IntensityMaxP1 = float64(historicalValues.Imax)
+		timeSerie.OverloadIntensity = float64(historicalValues.Adps)
 	}
 
+	timeSerie.ScheduleGroup = historicalValues.Hhphc
+
 	if isBase {
 		timeSerie.EnergyUsedIndex1 = float64(historicalValues.Base)
 	} else if isHCHP {
@@ -46,6 +193,12 @@ func ConvertHistoricalTicValueToTimeSerie(historicalValues *core.HistoricalTicVa
 		timeSerie.EnergyUsedIndex1 = float64(historicalValues.Ejphn)
 		timeSerie.EnergyUsedIndex2 = float64(historicalValues.Ejphpn)
 		timeSerie.ContractTypeNextDayNumber = strconv.FormatInt(int64(historicalValues.Pejp), 10)
+		timeSerie.EJPNotice = float64(historicalValues.Pejp)
+		timeSerie.TodayColor = ColorBlue
+		if strings.HasPrefix(historicalValues.Ptec, "PM") {
+			timeSerie.TodayColor = ColorRed
+		}
+		timeSerie.TomorrowColor = ColorUnknown
 	} else if isBBR {
 		timeSerie.EnergyUsedIndex1 = float64(historicalValues.Bbrhcjb)
 		timeSerie.EnergyUsedIndex2 = float64(historicalValues.Bbrhpjb)
@@ -54,6 +207,23 @@ func ConvertHistoricalTicValueToTimeSerie(historicalValues *core.HistoricalTicVa
 		timeSerie.EnergyUsedIndex5 = float64(historicalValues.Bbrhcjr)
 		timeSerie.EnergyUsedIndex6 = float64(historicalValues.Bbrhpjr)
 		timeSerie.ContractTypeNextDayNumber = historicalValues.Demain
+		timeSerie.TodayColor = historicalTempoColor(historicalValues.Ptec)
+		timeSerie.TomorrowColor = historicalTempoColor(historicalValues.Demain)
+	}
+
+	if historicalValues.Ppot != "" {
+		timeSerie.PhasePotentials = []float64{
+			float64(historicalValues.PhasePotential1),
+			float64(historicalValues.PhasePotential2),
+			float64(historicalValues.PhasePotential3),
+		}
+	}
+
+	if historicalValues.Motdetat != "" {
+		timeSerie.StateWordBits = make([]float64, 24)
+		for bit := range timeSerie.StateWordBits {
+			timeSerie.StateWordBits[bit] = float64(historicalValues.StateWord >> bit & 1)
+		}
 	}
 
 	return timeSerie
@@ -61,7 +231,7 @@ func ConvertHistoricalTicValueToTimeSerie(historicalValues *core.HistoricalTicVa
 
 // Convert Standard Tic Value to Time serie value
 func ConvertStandardTicValueToTimeSerie(standardValues *core.StandardTicValue) *LinkyTimeSerie {
-	return &LinkyTimeSerie{
+	timeSerie := &LinkyTimeSerie{
 		LinkyId:                            standardValues.Adsc,
 		Version:                            standardValues.Vtic,
 		LinkyDate:                          float64(standardValues.Date.Unix()),
@@ -100,23 +270,40 @@ func ConvertStandardTicValueToTimeSerie(standardValues *core.StandardTicValue) *
 		PowerUsedP2:                        float64(standardValues.Sinsts2),
 		PowerUsedP3:                        float64(standardValues.Sinsts3),
 		PowerUsedMax:                       float64(standardValues.Smaxsn),
+		PowerUsedMaxTime:                   unixSeconds(standardValues.SmaxsnDate),
 		PowerUsedMaxP1:                     float64(standardValues.Smaxsn1),
+		PowerUsedMaxP1Time:                 unixSeconds(standardValues.Smaxsn1Date),
 		PowerUsedMaxP2:                     float64(standardValues.Smaxsn2),
+		PowerUsedMaxP2Time:                 unixSeconds(standardValues.Smaxsn2Date),
 		PowerUsedMaxP3:                     float64(standardValues.Smaxsn3),
+		PowerUsedMaxP3Time:                 unixSeconds(standardValues.Smaxsn3Date),
 		PowerUsedMaxLastYear:               float64(standardValues.Smaxsnly),
+		PowerUsedMaxLastYearTime:           unixSeconds(standardValues.SmaxsnlyDate),
 		PowerUsedMaxLastYearP1:             float64(standardValues.Smaxsn1ly),
+		PowerUsedMaxLastYearP1Time:         unixSeconds(standardValues.Smaxsn1lyDate),
 		PowerUsedMaxLastYearP2:             float64(standardValues.Smaxsn2ly),
+		PowerUsedMaxLastYearP2Time:         unixSeconds(standardValues.Smaxsn2lyDate),
 		PowerUsedMaxLastYearP3:             float64(standardValues.Smaxsn3ly),
+		PowerUsedMaxLastYearP3Time:         unixSeconds(standardValues.Smaxsn3lyDate),
 		PowerProduced:                      float64(standardValues.Sinsti),
 		PowerProducedMax:                   float64(standardValues.Smaxin),
+		PowerProducedMaxTime:               unixSeconds(standardValues.SmaxinDate),
 		PowerProducedLastYear:              float64(standardValues.Smaxinly),
+		PowerProducedLastYearTime:          unixSeconds(standardValues.SmaxinlyDate),
 		UsedLoadCurvePoint:                 float64(standardValues.Ccasn),
+		UsedLoadCurvePointTime:             unixSeconds(standardValues.CcasnDate),
 		UsedLoadCurvePointLastYear:         float64(standardValues.Ccasnly),
+		UsedLoadCurvePointLastYearTime:     unixSeconds(standardValues.CcasnlyDate),
 		ProducedLoadCurvePoint:             float64(standardValues.Ccain),
+		ProducedLoadCurvePointTime:         unixSeconds(standardValues.CcainDate),
 		ProducedLoadCurvePointLastYear:     float64(standardValues.Ccainly),
+		ProducedLoadCurvePointLastYearTime: unixSeconds(standardValues.CcainlyDate),
 		AverageVoltageP1:                   float64(standardValues.Umoy1),
+		AverageVoltageP1Time:               unixSeconds(standardValues.Umoy1Date),
 		AverageVoltageP2:                   float64(standardValues.Umoy2),
+		AverageVoltageP2Time:               unixSeconds(standardValues.Umoy2Date),
 		AverageVoltageP3:                   float64(standardValues.Umoy3),
+		AverageVoltageP3Time:               unixSeconds(standardValues.Umoy3Date),
 		DryContactStatus:                   float64(standardValues.DryContactStatus),
 		CutOffDeviceStatus:                 float64(standardValues.CutOffDeviceStatus),
 		LinkyTerminalShieldStatus:          float64(standardValues.LinkyTerminalShieldStatus),
@@ -136,11 +323,17 @@ func ConvertStandardTicValueToTimeSerie(standardValues *core.StandardTicValue) *
 		MovingPeakNoticeStatus:             float64(standardValues.MovingPeakNoticeStatus),
 		MovingPeakStatus:                   float64(standardValues.MovingPeakStatus),
 		MovingPeakStart1:                   float64(standardValues.Dpm1),
+		MovingPeakStart1Time:               unixSeconds(standardValues.Dpm1Date),
 		MovingPeakEnd1:                     float64(standardValues.Fpm1),
+		MovingPeakEnd1Time:                 unixSeconds(standardValues.Fpm1Date),
 		MovingPeakStart2:                   float64(standardValues.Dpm2),
+		MovingPeakStart2Time:               unixSeconds(standardValues.Dpm2Date),
 		MovingPeakEnd2:                     float64(standardValues.Fpm2),
+		MovingPeakEnd2Time:                 unixSeconds(standardValues.Fpm2Date),
 		MovingPeakStart3:                   float64(standardValues.Dpm3),
+		MovingPeakStart3Time:               unixSeconds(standardValues.Dpm3Date),
 		MovingPeakEnd3:                     float64(standardValues.Fpm3),
+		MovingPeakEnd3Time:                 unixSeconds(standardValues.Fpm3Date),
 		Prm:                                standardValues.Prm,
 		Relay1:                             float64(standardValues.Relai1),
 		Relay2:                             float64(standardValues.Relai2),
@@ -155,5 +348,27 @@ func ConvertStandardTicValueToTimeSerie(standardValues *core.StandardTicValue) *
 		ContractTypeNextDayNumber:          strconv.FormatInt(int64(standardValues.Njourfnd), 10),
 		ContractTypeNextDayProfile:         standardValues.Pjourfnd,
 		PeakNextDayProfile:                 standardValues.Ppointe,
+		NextDaySwitches:                    standardValues.NextDayProfile,
+		Message1:                           standardValues.Msg1,
+		Message2:                           standardValues.Msg2,
 	}
+
+	calendar := strings.ToUpper(standardValues.Ngtf)
+	if strings.Contains(calendar, "TEMPO") ||
+		standardValues.TempoContractColorStatus != 0 || standardValues.TempoContractNextDayColorStatus != 0 {
+		timeSerie.TodayColor = standardTempoColors[standardValues.TempoContractColorStatus]
+		timeSerie.TomorrowColor = standardTempoColors[standardValues.TempoContractNextDayColorStatus]
+	} else if strings.Contains(calendar, "EJP") {
+		// Mobile peak in progress today, notice given the day before
+		timeSerie.TodayColor = ColorBlue
+		if standardValues.MovingPeakStatus != 0 {
+			timeSerie.TodayColor = ColorRed
+		}
+		timeSerie.TomorrowColor = ColorUnknown
+		if standardValues.MovingPeakNoticeStatus != 0 {
+			timeSerie.TomorrowColor = ColorRed
+		}
+	}
+
+	return timeSerie
 }
@@ -0,0 +1,141 @@
+package prom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestConvertHistoricalTariffDayColorTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name            string
+		values          core.HistoricalTicValue
+		today, tomorrow string
+	}{
+		{"base", core.HistoricalTicValue{Base: 1, Ptec: "TH.."}, "", ""},
+		{"tempo blue", core.HistoricalTicValue{Bbrhcjb: 1, Ptec: "HCJB", Demain: "----"}, ColorBlue, ColorUnknown},
+		{"tempo red", core.HistoricalTicValue{Bbrhcjb: 1, Ptec: "HPJR", Demain: "BLAN"}, ColorRed, ColorWhite},
+		{"ejp normal", core.HistoricalTicValue{Ejphn: 1, Ptec: "HN.."}, ColorBlue, ColorUnknown},
+		{"ejp peak", core.HistoricalTicValue{Ejphn: 1, Ptec: "PM.."}, ColorRed, ColorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			timeSerie := ConvertHistoricalTicValueToTimeSerie(&tt.values)
+
+			// Then
+			if timeSerie.TodayColor != tt.today || timeSerie.TomorrowColor != tt.tomorrow {
+				t.Errorf("got %q %q, want %q %q", timeSerie.TodayColor, timeSerie.TomorrowColor, tt.today, tt.tomorrow)
+			}
+		})
+	}
+}
+
+func TestConvertStandardTariffDayColorTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name            string
+		values          core.StandardTicValue
+		today, tomorrow string
+	}{
+		{"base", core.StandardTicValue{Ngtf: "BASE"}, "", ""},
+		{"tempo", core.StandardTicValue{Ngtf: "TEMPO", TempoContractColorStatus: 2}, ColorWhite, ColorUnknown},
+		{"ejp notice", core.StandardTicValue{Ngtf: "EJP", MovingPeakNoticeStatus: 1}, ColorBlue, ColorRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			timeSerie := ConvertStandardTicValueToTimeSerie(&tt.values)
+
+			// Then
+			if timeSerie.TodayColor != tt.today || timeSerie.TomorrowColor != tt.tomorrow {
+				t.Errorf("got %q %q, want %q %q", timeSerie.TodayColor, timeSerie.TomorrowColor, tt.today, tt.tomorrow)
+			}
+		})
+	}
+}
+
+func TestSerieFieldsAreNumericFields(t *testing.T) {
+	// Given
+	serieType := reflect.TypeFor[LinkyTimeSerie]()
+
+	for _, serieFields := range []map[string][]string{standardSerieFields, historicalSerieFields} {
+		for label, fields := range serieFields {
+			for _, name := range fields {
+				// When
+				field, found := serieType.FieldByName(name)
+
+				// Then
+				if !found || field.Type.Kind() != reflect.Float64 {
+					t.Errorf("%s: %s is not a numeric field of the time serie", label, name)
+				}
+			}
+		}
+	}
+}
+
+// Decode a historical frame from its groups, given as label and value
+func historicalFrame(groups ...[2]string) *core.TicFrame {
+	frame := &core.TicFrame{Historical: &core.HistoricalTicValue{}}
+	for _, group := range groups {
+		frame.Groups = append(frame.Groups, core.TicGroup{Label: group[0], Value: group[1]})
+		_ = frame.Historical.ParseParam(group[0], []string{group[1], ""})
+	}
+	return frame
+}
+
+// Frames of single-phase and three-phase meters
+var (
+	singlePhaseFrame = historicalFrame([2]string{"ADCO", "XXXX"}, [2]string{"ISOUSC", "30"},
+		[2]string{"IINST", "002"}, [2]string{"IMAX", "090"}, [2]string{"PAPP", "00450"})
+	idleThreePhaseFrame = historicalFrame([2]string{"ADCO", "XXXX"}, [2]string{"ISOUSC", "20"},
+		[2]string{"IINST1", "000"}, [2]string{"IINST2", "000"}, [2]string{"IINST3", "000"},
+		[2]string{"IMAX1", "012"}, [2]string{"IMAX2", "008"}, [2]string{"IMAX3", "000"},
+		[2]string{"PMAX", "03200"}, [2]string{"PAPP", "00000"}, [2]string{"PPOT", "00"})
+	loadedThreePhaseFrame = historicalFrame([2]string{"ADCO", "XXXX"}, [2]string{"ISOUSC", "20"},
+		[2]string{"IINST1", "004"}, [2]string{"IINST2", "001"}, [2]string{"IINST3", "002"},
+		[2]string{"IMAX1", "012"}, [2]string{"IMAX2", "008"}, [2]string{"IMAX3", "010"},
+		[2]string{"PMAX", "03200"}, [2]string{"PAPP", "01650"}, [2]string{"PPOT", "00"})
+)
+
+func TestConvertHistoricalPhasesTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name           string
+		frame          *core.TicFrame
+		threePhase     bool
+		referencePower float64
+		intensities    [3]float64
+		intensityMaxes [3]float64
+		powerMax       float64
+	}{
+		{"single-phase", singlePhaseFrame, false, 6, [3]float64{2, 0, 0}, [3]float64{90, 0, 0}, 0},
+		{"idle three-phase", idleThreePhaseFrame, true, 12, [3]float64{0, 0, 0}, [3]float64{12, 8, 0}, 3200},
+		{"loaded three-phase", loadedThreePhaseFrame, true, 12, [3]float64{4, 1, 2}, [3]float64{12, 8, 10}, 3200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			ts := ConvertFrameToTimeSerie(tt.frame)
+
+			// Then
+			if ts.ThreePhase != tt.threePhase || ts.ReferencePower != tt.referencePower {
+				t.Errorf("got three-phase %t reference power %v", ts.ThreePhase, ts.ReferencePower)
+			}
+			if intensities := [3]float64{ts.IntensityP1, ts.IntensityP2, ts.IntensityP3}; intensities != tt.intensities {
+				t.Errorf("got intensities %v, want %v", intensities, tt.intensities)
+			}
+			if maxes := [3]float64{ts.IntensityMaxP1, ts.IntensityMaxP2, ts.IntensityMaxP3}; maxes != tt.intensityMaxes {
+				t.Errorf("got intensity maxes %v, want %v", maxes, tt.intensityMaxes)
+			}
+			if ts.PowerUsedMaxThreePhase != tt.powerMax || ts.Sent("PowerUsedMaxThreePhase") != tt.threePhase {
+				t.Errorf("got power max %v sent %t", ts.PowerUsedMaxThreePhase, ts.Sent("PowerUsedMaxThreePhase"))
+			}
+		})
+	}
+}
@@ -1,39 +1,95 @@
 package prom
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/syberalexis/linky-exporter/pkg/core"
 )
 
 // LinkyExporter object to run exporter server and expose metrics
 type LinkyExporter struct {
-	Address string
-	Port    int
+	Address         string
+	Port            int
+	ListenAddresses []string // Addresses to listen on, Address and Port if empty
+	SystemdSocket   bool     // Use systemd socket activation listeners instead of ListenAddresses
+	WebConfigFile   string   // exporter-toolkit web configuration enabling TLS and basic authentication
+	DisabledMetrics []string
 }
 
-// Run method to run http exporter server
-func (exporter *LinkyExporter) Run(connector *core.LinkyConnector) {
-	slog.Info(fmt.Sprintf("Beginning to serve on port :%d", exporter.Port))
+// RegisterMeters registers a collector for each meter, with a meter label holding its name. With frameTimestamps,
+// metrics of the frame values carry the frame reception time.
+func RegisterMeters(registerer prometheus.Registerer, meters map[string]*core.LinkyConnector, disabledMetrics []string, frameTimestamps bool) error {
+	for _, name := range slices.Sorted(maps.Keys(meters)) {
+		collector := NewLinkyCollector(meters[name])
+		collector.frameTimestamps = frameTimestamps
+		for _, metric := range disabledMetrics {
+			err := collector.Disable(metric)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := prometheus.WrapRegistererWith(prometheus.Labels{"meter": name}, registerer).Register(collector)
+		if err != nil {
+			return fmt.Errorf("unable to register meter %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ShutdownTimeout bounds the wait for in-flight requests when stopping the server
+const ShutdownTimeout = 10 * time.Second
+
+// Run method to run http exporter server until the context is done, metrics of each meter are labeled with its name
+func (exporter *LinkyExporter) Run(ctx context.Context, meters map[string]*core.LinkyConnector) error {
+	err := RegisterMeters(prometheus.DefaultRegisterer, meters, exporter.DisabledMetrics, false)
+	if err != nil {
+		return err
+	}
 
-	prometheus.MustRegister(NewLinkyCollector(connector))
 	http.Handle("/metrics", promhttp.Handler())
 
 	// Create server with timeouts
 	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", exporter.Address, exporter.Port),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	err := server.ListenAndServe()
-	if err != nil {
-		slog.Error("Error while serving metrics", "error", err)
+	// Stop accepting connections and drain in-flight scrapes once the context is done
+	stopped := make(chan error, 1)
+	stop := context.AfterFunc(ctx, func() {
+		slog.Info("Shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		stopped <- server.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	addresses := exporter.ListenAddresses
+	if len(addresses) == 0 {
+		addresses = []string{net.JoinHostPort(exporter.Address, strconv.Itoa(exporter.Port))}
+	}
+	flags := &web.FlagConfig{
+		WebListenAddresses: &addresses,
+		WebSystemdSocket:   &exporter.SystemdSocket,
+		WebConfigFile:      &exporter.WebConfigFile,
+	}
+	err = web.ListenAndServe(server, flags, slog.Default())
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return <-stopped
 }
@@ -0,0 +1,46 @@
+package prom
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MetricGroups are the metric names of each group, linky_up doesn't belong to a group and is always exported
+var MetricGroups = map[string][]string{
+	"energy": {"linky_energy_total", "linky_energy", "linky_reactive_energy_total"},
+	"power": {"linky_power", "linky_power_last_year", "linky_power_max", "linky_power_reference",
+		"linky_power_max_three_phase", "linky_power_max_timestamp_seconds", "linky_power_last_year_timestamp_seconds"},
+	"electrical": {"linky_intensity", "linky_intensity_max", "linky_overload_intensity", "linky_voltage",
+		"linky_voltage_average", "linky_voltage_average_timestamp_seconds", "linky_phase_potential_present"},
+	"load_curve": {"linky_load_curve_point", "linky_load_curve_point_last_year",
+		"linky_load_curve_point_timestamp_seconds", "linky_load_curve_point_last_year_timestamp_seconds"},
+	"tariff": {"linky_tariff_day_color", "linky_movable_peak", "linky_movable_peak_timestamp_seconds",
+		"linky_ejp_notice_minutes", "linky_schedule_info", "linky_provider_day_info", "linky_next_day_switch_start_seconds"},
+	"status": {"linky_timestamp", "linky_status", "linky_relay", "linky_state_word_bit", "linky_message_info"},
+	"exporter": {"linky_frame_checksum_errors_total", "linky_exporter_frames_total", "linky_exporter_frame_errors_total",
+		"linky_exporter_last_frame_timestamp_seconds", "linky_exporter_frame_read_duration_seconds",
+		"linky_exporter_unknown_labels_total"},
+}
+
+// DisabledByGroups returns the metrics of the groups not enabled, none when enabled is empty
+func DisabledByGroups(enabled []string) ([]string, error) {
+	if len(enabled) == 0 {
+		return nil, nil
+	}
+	for _, group := range enabled {
+		if _, found := MetricGroups[group]; !found {
+			return nil, fmt.Errorf("unknown metric group %q, must be one of %s",
+				group, strings.Join(slices.Sorted(maps.Keys(MetricGroups)), ", "))
+		}
+	}
+
+	var disabled []string
+	for _, group := range slices.Sorted(maps.Keys(MetricGroups)) {
+		if !slices.Contains(enabled, group) {
+			disabled = append(disabled, MetricGroups[group]...)
+		}
+	}
+	return disabled, nil
+}
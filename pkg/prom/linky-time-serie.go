@@ -1,5 +1,11 @@
 package prom
 
+import (
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
 type LinkyTimeSerie struct {
 	LinkyId                            string
 	Version                            string
@@ -39,23 +45,40 @@ type LinkyTimeSerie struct {
 	PowerUsedP2                        float64
 	PowerUsedP3                        float64
 	PowerUsedMax                       float64
+	PowerUsedMaxTime                   float64
 	PowerUsedMaxP1                     float64
+	PowerUsedMaxP1Time                 float64
 	PowerUsedMaxP2                     float64
+	PowerUsedMaxP2Time                 float64
 	PowerUsedMaxP3                     float64
+	PowerUsedMaxP3Time                 float64
 	PowerUsedMaxLastYear               float64
+	PowerUsedMaxLastYearTime           float64
 	PowerUsedMaxLastYearP1             float64
+	PowerUsedMaxLastYearP1Time         float64
 	PowerUsedMaxLastYearP2             float64
+	PowerUsedMaxLastYearP2Time         float64
 	PowerUsedMaxLastYearP3             float64
+	PowerUsedMaxLastYearP3Time         float64
 	PowerProduced                      float64
 	PowerProducedMax                   float64
+	PowerProducedMaxTime               float64
 	PowerProducedLastYear              float64
+	PowerProducedLastYearTime          float64
 	UsedLoadCurvePoint                 float64
+	UsedLoadCurvePointTime             float64
 	UsedLoadCurvePointLastYear         float64
+	UsedLoadCurvePointLastYearTime     float64
 	ProducedLoadCurvePoint             float64
+	ProducedLoadCurvePointTime         float64
 	ProducedLoadCurvePointLastYear     float64
+	ProducedLoadCurvePointLastYearTime float64
 	AverageVoltageP1                   float64
+	AverageVoltageP1Time               float64
 	AverageVoltageP2                   float64
+	AverageVoltageP2Time               float64
 	AverageVoltageP3                   float64
+	AverageVoltageP3Time               float64
 	DryContactStatus                   float64
 	CutOffDeviceStatus                 float64
 	LinkyTerminalShieldStatus          float64
@@ -75,11 +98,17 @@ type LinkyTimeSerie struct {
 	MovingPeakNoticeStatus             float64
 	MovingPeakStatus                   float64
 	MovingPeakStart1                   float64
+	MovingPeakStart1Time               float64
 	MovingPeakEnd1                     float64
+	MovingPeakEnd1Time                 float64
 	MovingPeakStart2                   float64
+	MovingPeakStart2Time               float64
 	MovingPeakEnd2                     float64
+	MovingPeakEnd2Time                 float64
 	MovingPeakStart3                   float64
+	MovingPeakStart3Time               float64
 	MovingPeakEnd3                     float64
+	MovingPeakEnd3Time                 float64
 	Prm                                string
 	Relay1                             float64
 	Relay2                             float64
@@ -94,6 +123,27 @@ type LinkyTimeSerie struct {
 	ContractTypeNextDayNumber          string
 	ContractTypeNextDayProfile         string
 	PeakNextDayProfile                 string
-	// Message1 string
-	// Message2 string
+	NextDaySwitches                    []core.DaySwitch
+	IntensityMaxP1                     float64
+	IntensityMaxP2                     float64
+	IntensityMaxP3                     float64
+	PowerUsedMaxThreePhase             float64
+	OverloadIntensity                  float64
+	ThreePhase                         bool // Compteur triphasé en mode historique
+	EJPNotice                          float64
+	TodayColor                         string // Couleur du jour tempo ou EJP, vide pour les autres contrats
+	TomorrowColor                      string // Couleur du lendemain tempo ou EJP, vide pour les autres contrats
+	ScheduleGroup                      string
+	PhasePotentials                    []float64 // Présence des potentiels par phase, nil si non triphasé
+	StateWordBits                      []float64 // Bits du mot d'état, nil si absent
+	Message1                           string
+	Message2                           string
+
+	ReceivedAt time.Time       // Reception time of the frame
+	sent       map[string]bool // Numeric fields decoded from groups of the frame, by field name
+}
+
+// Sent checks if a numeric field has been decoded from a group of the frame, even at zero
+func (ts *LinkyTimeSerie) Sent(field string) bool {
+	return ts.sent[field]
 }
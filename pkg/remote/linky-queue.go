@@ -0,0 +1,200 @@
+package remote
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// MaxMemoryRequests is the number of requests kept in memory without WAL, the oldest are dropped first
+	MaxMemoryRequests = 1000
+	// DefaultWALMaxSize is the default maximum size in bytes of the WAL directory
+	DefaultWALMaxSize = 100 << 20
+)
+
+// Suffix of the WAL segments, one request per segment
+const segmentExtension = ".rw"
+
+// Queue keeps requests in order until sent, in a WAL directory or in memory. Safe for concurrent use.
+type Queue struct {
+	dir      string
+	maxSize  int64 // Maximum size in bytes of the WAL segments, unlimited if not positive
+	mutex    sync.Mutex
+	requests [][]byte  // In memory requests
+	segments []segment // WAL segments, oldest first
+	size     int64     // Size in bytes of the WAL segments
+	peeked   bool      // The oldest request is being sent and must not be dropped
+	pushed   chan struct{}
+}
+
+type segment struct {
+	number uint64
+	size   int64
+}
+
+// NewQueue opens the queue, segments left in the WAL directory by a previous run are kept within maxSize
+func NewQueue(dir string, maxSize int64) (*Queue, error) {
+	queue := &Queue{dir: dir, maxSize: maxSize, pushed: make(chan struct{}, 1)}
+	if dir == "" {
+		return queue, nil
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		number, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(file), segmentExtension), 10, 64)
+		if err != nil {
+			slog.Warn("Ignoring unknown WAL file", "file", file)
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		queue.segments = append(queue.segments, segment{number: number, size: info.Size()})
+		queue.size += info.Size()
+	}
+	slices.SortFunc(queue.segments, func(a, b segment) int { return cmp.Compare(a.number, b.number) })
+	if len(queue.segments) > 0 {
+		slog.Info("Remote write WAL loaded", "directory", dir, "requests", len(queue.segments))
+		queue.trim()
+		queue.notify()
+	}
+	return queue, nil
+}
+
+// Push adds a request at the end of the queue, dropping the oldest requests when the queue is full
+func (queue *Queue) Push(request []byte) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.dir == "" {
+		queue.requests = append(queue.requests, request)
+		if len(queue.requests) > MaxMemoryRequests {
+			slog.Warn("Remote write queue full, dropping oldest request")
+			queue.requests = slices.Delete(queue.requests, queue.oldest(), queue.oldest()+1)
+		}
+	} else {
+		var number uint64
+		if len(queue.segments) > 0 {
+			number = queue.segments[len(queue.segments)-1].number + 1
+		}
+		err := os.WriteFile(queue.path(number), request, 0o600)
+		if err != nil {
+			return err
+		}
+		queue.segments = append(queue.segments, segment{number: number, size: int64(len(request))})
+		queue.size += int64(len(request))
+		queue.trim()
+	}
+
+	queue.notify()
+	return nil
+}
+
+// Peek returns the oldest request, nil if the queue is empty
+func (queue *Queue) Peek() ([]byte, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.dir == "" {
+		if len(queue.requests) == 0 {
+			return nil, nil
+		}
+		queue.peeked = true
+		return queue.requests[0], nil
+	}
+	if len(queue.segments) == 0 {
+		return nil, nil
+	}
+	request, err := os.ReadFile(queue.path(queue.segments[0].number))
+	if err != nil {
+		// Skip unreadable segments rather than blocking the queue
+		queue.size -= queue.segments[0].size
+		queue.segments = queue.segments[1:]
+		return nil, err
+	}
+	queue.peeked = true
+	return request, nil
+}
+
+// Pop removes the oldest request
+func (queue *Queue) Pop() error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.peeked = false
+	if queue.dir == "" {
+		if len(queue.requests) > 0 {
+			queue.requests = queue.requests[1:]
+		}
+		return nil
+	}
+	if len(queue.segments) == 0 {
+		return nil
+	}
+	return queue.remove(0)
+}
+
+// Len returns the number of requests in the queue
+func (queue *Queue) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return len(queue.requests) + len(queue.segments)
+}
+
+// Pushed returns a channel receiving a value after requests have been pushed
+func (queue *Queue) Pushed() <-chan struct{} {
+	return queue.pushed
+}
+
+func (queue *Queue) notify() {
+	select {
+	case queue.pushed <- struct{}{}:
+	default:
+	}
+}
+
+// Index of the oldest request which can be dropped, the one being sent is kept
+func (queue *Queue) oldest() int {
+	if queue.peeked {
+		return 1
+	}
+	return 0
+}
+
+// Drop the oldest segments until the WAL fits in maxSize, the newest is always kept
+func (queue *Queue) trim() {
+	for queue.maxSize > 0 && queue.size > queue.maxSize && len(queue.segments) > queue.oldest()+1 {
+		index := queue.oldest()
+		slog.Warn("Remote write WAL full, dropping oldest request", "segment", queue.path(queue.segments[index].number), "max_size", queue.maxSize)
+		if err := queue.remove(index); err != nil {
+			slog.Error("Unable to remove remote write WAL segment", "error", err)
+		}
+	}
+}
+
+// Remove a segment from the queue and the WAL directory
+func (queue *Queue) remove(index int) error {
+	removed := queue.segments[index]
+	queue.segments = slices.Delete(queue.segments, index, index+1)
+	queue.size -= removed.size
+	return os.Remove(queue.path(removed.number))
+}
+
+func (queue *Queue) path(number uint64) string {
+	return filepath.Join(queue.dir, fmt.Sprintf("%020d%s", number, segmentExtension))
+}
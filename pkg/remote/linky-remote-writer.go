@@ -0,0 +1,255 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+)
+
+// DefaultInterval between two collections of the meters metrics
+const DefaultInterval = 15 * time.Second
+
+const (
+	// WriteTimeout bounds each remote write request
+	WriteTimeout = 30 * time.Second
+	// Initial delay before sending again a failed request, doubled on each new failure
+	MinRetryDelay = 1 * time.Second
+	// Maximum delay before sending again a failed request
+	MaxRetryDelay = 1 * time.Minute
+)
+
+// errPermanent marks requests rejected by the receiver, not worth retrying
+var errPermanent = errors.New("request rejected")
+
+// LinkyRemoteWriter pushes the meters metrics with the Prometheus remote write protocol, for meters
+// which can't be scraped. Samples of the frame values carry the frame reception time.
+// Requests are queued, on disk if WALDir is set, and sent in order until accepted.
+type LinkyRemoteWriter struct {
+	URL             string
+	Username        string
+	Password        string
+	BearerToken     string
+	Interval        time.Duration // Interval between two collections
+	WALDir          string        // Directory keeping requests not sent yet across outages and restarts, in memory if empty
+	WALMaxSize      int64         // Maximum size in bytes of the WAL directory, the oldest requests are dropped first
+	DisabledMetrics []string
+
+	client   http.Client
+	registry *prometheus.Registry
+	lastSent map[string]time.Time // Reception time of the last frame sent by meter
+	queue    *Queue
+}
+
+// Run collects and pushes metrics until the context is done, requests not sent yet stay in the WAL
+func (writer *LinkyRemoteWriter) Run(ctx context.Context, meters map[string]*core.LinkyConnector) error {
+	writer.client.Timeout = WriteTimeout
+	writer.lastSent = make(map[string]time.Time)
+	writer.registry = prometheus.NewRegistry()
+	err := prom.RegisterMeters(writer.registry, meters, writer.DisabledMetrics, true)
+	if err != nil {
+		return err
+	}
+	writer.queue, err = NewQueue(writer.WALDir, writer.WALMaxSize)
+	if err != nil {
+		return err
+	}
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		writer.send(ctx)
+	}()
+
+	ticker := time.NewTicker(writer.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			<-sent
+			if pending := writer.queue.Len(); pending > 0 && writer.WALDir == "" {
+				slog.Warn("Remote write requests not sent", "requests", pending)
+			}
+			return nil
+		case now := <-ticker.C:
+			series, err := writer.collect(now)
+			if err != nil {
+				slog.Error("Unable to collect metrics", "error", err)
+				continue
+			}
+			if len(series) == 0 {
+				continue
+			}
+			err = writer.queue.Push(snappy.Encode(nil, MarshalWriteRequest(series)))
+			if err != nil {
+				slog.Error("Unable to queue remote write request", "error", err)
+			}
+		}
+	}
+}
+
+// Collect the meters metrics, frame values are only collected once per frame with the reception time of the
+// frame they were read from
+func (writer *LinkyRemoteWriter) collect(now time.Time) ([]TimeSerie, error) {
+	families, err := writer.registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	received := make(map[string]time.Time)
+	var series []TimeSerie
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var labels []Label
+			meter := ""
+			for _, pair := range metric.GetLabel() {
+				labels = append(labels, Label{Name: pair.GetName(), Value: pair.GetValue()})
+				if pair.GetName() == "meter" {
+					meter = pair.GetValue()
+				}
+			}
+
+			date := now
+			if !prom.IsConnectorMetric(family.GetName()) {
+				date = time.UnixMilli(metric.GetTimestampMs())
+				if !date.After(writer.lastSent[meter]) {
+					continue
+				}
+				received[meter] = date
+			}
+			series = append(series, metricSeries(family.GetName(), labels, metric, date.UnixMilli())...)
+		}
+	}
+
+	for meter, date := range received {
+		writer.lastSent[meter] = date
+	}
+	return series, nil
+}
+
+// Series of a metric, its value for counters, gauges and untyped metrics, the _bucket, _sum and _count
+// series for histograms
+func metricSeries(name string, labels []Label, metric *dto.Metric, timestamp int64) []TimeSerie {
+	serie := func(name string, value float64, extra ...Label) TimeSerie {
+		serieLabels := append([]Label{{Name: "__name__", Value: name}}, labels...)
+		return TimeSerie{Labels: append(serieLabels, extra...), Samples: []Sample{{Value: value, Timestamp: timestamp}}}
+	}
+
+	switch {
+	case metric.Gauge != nil:
+		return []TimeSerie{serie(name, metric.Gauge.GetValue())}
+	case metric.Counter != nil:
+		return []TimeSerie{serie(name, metric.Counter.GetValue())}
+	case metric.Untyped != nil:
+		return []TimeSerie{serie(name, metric.Untyped.GetValue())}
+	case metric.Histogram != nil:
+		histogram := metric.Histogram
+		var series []TimeSerie
+		infinite := false
+		for _, bucket := range histogram.GetBucket() {
+			infinite = math.IsInf(bucket.GetUpperBound(), 1)
+			bound := strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)
+			series = append(series, serie(name+"_bucket", float64(bucket.GetCumulativeCount()), Label{Name: "le", Value: bound}))
+		}
+		if !infinite {
+			series = append(series, serie(name+"_bucket", float64(histogram.GetSampleCount()), Label{Name: "le", Value: "+Inf"}))
+		}
+		return append(series,
+			serie(name+"_sum", histogram.GetSampleSum()),
+			serie(name+"_count", float64(histogram.GetSampleCount())),
+		)
+	default:
+		slog.Debug("Skipping remote write of unsupported metric type", "metric", name)
+		return nil
+	}
+}
+
+// Send queued requests in order until the context is done, retrying with backoff while the receiver fails
+func (writer *LinkyRemoteWriter) send(ctx context.Context) {
+	delay := MinRetryDelay
+	for {
+		request, err := writer.queue.Peek()
+		if err != nil {
+			slog.Error("Unable to read queued remote write request", "error", err)
+			continue
+		}
+		if request == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-writer.queue.Pushed():
+			}
+			continue
+		}
+
+		err = writer.write(ctx, request)
+		if err == nil || errors.Is(err, errPermanent) {
+			if err != nil {
+				slog.Error("Dropping remote write request", "url", writer.URL, "error", err)
+			}
+			delay = MinRetryDelay
+			if err := writer.queue.Pop(); err != nil {
+				slog.Error("Unable to remove sent remote write request", "error", err)
+			}
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("Failed to send remote write request", "url", writer.URL, "error", err, "retry", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, MaxRetryDelay)
+	}
+}
+
+// Send a snappy compressed request
+func (writer *LinkyRemoteWriter) write(ctx context.Context, request []byte) error {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, writer.URL, bytes.NewReader(request))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPermanent, err)
+	}
+	httpRequest.Header.Set("Content-Encoding", "snappy")
+	httpRequest.Header.Set("Content-Type", "application/x-protobuf")
+	httpRequest.Header.Set("User-Agent", "linky-exporter")
+	httpRequest.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if writer.BearerToken != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+writer.BearerToken)
+	} else if writer.Username != "" {
+		httpRequest.SetBasicAuth(writer.Username, writer.Password)
+	}
+
+	response, err := writer.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("unexpected status %s: %s", response.Status, strings.TrimSpace(string(body)))
+	// Only server errors and rate limiting are retried, like Prometheus does
+	if response.StatusCode < http.StatusInternalServerError && response.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", errPermanent, err)
+	}
+	return err
+}
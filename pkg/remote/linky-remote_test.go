@@ -0,0 +1,222 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/prom"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMarshalWriteRequest(t *testing.T) {
+	// Given
+	series := []TimeSerie{{
+		Labels:  []Label{{Name: "meter", Value: "house"}, {Name: "__name__", Value: "linky_power_used"}},
+		Samples: []Sample{{Value: 750, Timestamp: 1700000000000}},
+	}}
+	expected := []byte{
+		0x0a, 0x40, // timeseries
+		0x0a, 0x1c, 0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', 0x12, 0x10, 'l', 'i', 'n', 'k', 'y', '_', 'p', 'o', 'w', 'e', 'r', '_', 'u', 's', 'e', 'd',
+		0x0a, 0x0e, 0x0a, 0x05, 'm', 'e', 't', 'e', 'r', 0x12, 0x05, 'h', 'o', 'u', 's', 'e',
+		0x12, 0x10, 0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x70, 0x87, 0x40, 0x10, 0x80, 0xd0, 0x95, 0xff, 0xbc, 0x31,
+	}
+
+	// When
+	request := MarshalWriteRequest(series)
+
+	// Then
+	if !bytes.Equal(request, expected) {
+		t.Errorf("got % x, want % x", request, expected)
+	}
+}
+
+func TestSendKeepsRequestsUntilAccepted(t *testing.T) {
+	// Given
+	var bodies [][]byte
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	writer := LinkyRemoteWriter{URL: server.URL, BearerToken: "secret"}
+	queue, _ := NewQueue(dir, 0)
+	_ = queue.Push(snappy.Encode(nil, []byte("first")))
+	_ = queue.Push(snappy.Encode(nil, []byte("second")))
+	request, _ := queue.Peek()
+
+	// When
+	failed := writer.write(context.Background(), request)
+	fail = false
+	writer.queue, _ = NewQueue(dir, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for writer.queue.Len() > 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+	}()
+	writer.send(ctx)
+
+	// Then
+	if failed == nil {
+		t.Errorf("expected an error while the receiver is unavailable")
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("got %d requests, want 2", len(bodies))
+	}
+	for i, expected := range []string{"first", "second"} {
+		body, err := snappy.Decode(nil, bodies[i])
+		if err != nil || string(body) != expected {
+			t.Errorf("got %q (%v), want %q", body, err, expected)
+		}
+	}
+}
+
+func TestMetricSeriesOfHistogram(t *testing.T) {
+	// Given
+	metric := &dto.Metric{Histogram: &dto.Histogram{
+		SampleCount: proto.Uint64(3),
+		SampleSum:   proto.Float64(1.5),
+		Bucket: []*dto.Bucket{
+			{UpperBound: proto.Float64(0.25), CumulativeCount: proto.Uint64(1)},
+			{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(2)},
+		},
+	}}
+	meter := []Label{{Name: "meter", Value: "house"}}
+	expected := []struct {
+		name  string
+		le    string
+		value float64
+	}{
+		{"linky_exporter_frame_read_duration_seconds_bucket", "0.25", 1},
+		{"linky_exporter_frame_read_duration_seconds_bucket", "1", 2},
+		{"linky_exporter_frame_read_duration_seconds_bucket", "+Inf", 3},
+		{"linky_exporter_frame_read_duration_seconds_sum", "", 1.5},
+		{"linky_exporter_frame_read_duration_seconds_count", "", 3},
+	}
+
+	// When
+	series := metricSeries("linky_exporter_frame_read_duration_seconds", meter, metric, 1700000000000)
+
+	// Then
+	if len(series) != len(expected) {
+		t.Fatalf("got %d series, want %d", len(series), len(expected))
+	}
+	for i, serie := range series {
+		labels := make(map[string]string)
+		for _, label := range serie.Labels {
+			labels[label.Name] = label.Value
+		}
+		if labels["__name__"] != expected[i].name || labels["le"] != expected[i].le || labels["meter"] != "house" ||
+			serie.Samples[0].Value != expected[i].value || serie.Samples[0].Timestamp != 1700000000000 {
+			t.Errorf("got serie %+v, want %+v", serie, expected[i])
+		}
+	}
+}
+
+func TestQueueDropsOldestSegments(t *testing.T) {
+	// Given
+	dir := t.TempDir()
+	queue, _ := NewQueue(dir, 10)
+	_ = queue.Push([]byte("first"))
+	sending, _ := queue.Peek()
+
+	// When
+	_ = queue.Push([]byte("second"))
+	_ = queue.Push([]byte("third"))
+	_ = queue.Pop()
+	next, _ := queue.Peek()
+	reopened, _ := NewQueue(dir, 4)
+
+	// Then
+	if string(sending) != "first" || string(next) != "third" {
+		t.Errorf("got requests %q then %q, want the request being sent kept and the second dropped", sending, next)
+	}
+	if queue.Len() != 1 {
+		t.Errorf("got %d requests, want 1", queue.Len())
+	}
+	if request, _ := reopened.Peek(); reopened.Len() != 1 || string(request) != "third" {
+		t.Errorf("got %d requests after reopening, first %q", reopened.Len(), request)
+	}
+}
+
+// Serve a single historical frame then keep the connection open until the test ends
+func serveFrame(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("\x02\nADCO 021728123456 @\r\nISOUSC 30 9\r\nPAPP 00800 )\r\x03"))
+		<-t.Context().Done()
+		_ = conn.Close()
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestCollectTimestampsFrameValues(t *testing.T) {
+	// Given
+	connector := &core.LinkyConnector{Mode: core.Historical, Device: serveFrame(t)}
+	connector.Start()
+	defer connector.Stop()
+	for connector.LastFrame() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	writer := LinkyRemoteWriter{lastSent: make(map[string]time.Time), registry: prometheus.NewRegistry()}
+	_ = prom.RegisterMeters(writer.registry, map[string]*core.LinkyConnector{"house": connector}, nil, true)
+	now := time.Now().Add(time.Minute)
+
+	// When
+	first, err := writer.collect(now)
+	second, _ := writer.collect(now)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	timestamps := func(series []TimeSerie) map[string]int64 {
+		result := make(map[string]int64)
+		for _, serie := range series {
+			for _, label := range serie.Labels {
+				if label.Name == "__name__" {
+					result[label.Value] = serie.Samples[0].Timestamp
+				}
+			}
+		}
+		return result
+	}
+	received := connector.LastFrame().ReceivedAt.UnixMilli()
+	if got := timestamps(first); got["linky_power"] != received || got["linky_up"] != now.UnixMilli() {
+		t.Errorf("got timestamps %v, want frame values at %d", got, received)
+	}
+	if got := timestamps(second); got["linky_power"] != 0 || got["linky_up"] != now.UnixMilli() {
+		t.Errorf("got timestamps %v, want frame values sent once", got)
+	}
+}
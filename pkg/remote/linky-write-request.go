@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Label of a time serie, __name__ holds the metric name
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample of a time serie, timestamp in milliseconds
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// TimeSerie is a serie of samples sharing the same labels
+type TimeSerie struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Field numbers of the remote write protobuf messages
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// MarshalWriteRequest encodes time series as a remote write 1.0 prometheus.WriteRequest protobuf message,
+// labels are sorted by name as required by receivers
+func MarshalWriteRequest(series []TimeSerie) []byte {
+	var request []byte
+	for _, serie := range series {
+		labels := slices.Clone(serie.Labels)
+		slices.SortFunc(labels, func(a, b Label) int { return strings.Compare(a.Name, b.Name) })
+
+		var message []byte
+		for _, label := range labels {
+			var field []byte
+			field = protowire.AppendTag(field, labelName, protowire.BytesType)
+			field = protowire.AppendString(field, label.Name)
+			field = protowire.AppendTag(field, labelValue, protowire.BytesType)
+			field = protowire.AppendString(field, label.Value)
+			message = protowire.AppendTag(message, timeSeriesLabels, protowire.BytesType)
+			message = protowire.AppendBytes(message, field)
+		}
+		for _, sample := range serie.Samples {
+			var field []byte
+			field = protowire.AppendTag(field, sampleValue, protowire.Fixed64Type)
+			field = protowire.AppendFixed64(field, math.Float64bits(sample.Value))
+			field = protowire.AppendTag(field, sampleTimestamp, protowire.VarintType)
+			field = protowire.AppendVarint(field, uint64(sample.Timestamp))
+			message = protowire.AppendTag(message, timeSeriesSamples, protowire.BytesType)
+			message = protowire.AppendBytes(message, field)
+		}
+
+		request = protowire.AppendTag(request, writeRequestTimeseries, protowire.BytesType)
+		request = protowire.AppendBytes(request, message)
+	}
+	return request
+}